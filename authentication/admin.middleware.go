@@ -0,0 +1,22 @@
+package authentication
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/conf"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
+)
+
+// AuthorizeAdmin only lets requests carrying the admin api key through
+func AuthorizeAdmin(ctx *gin.Context) {
+	apiKey := ctx.GetHeader("X-Api-Key")
+	if conf.AdminApiKey == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(conf.AdminApiKey)) != 1 {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": true, "message": "Invalid api key"})
+		ctx.Abort()
+		return
+	}
+	ctx.Set(constants.AdminMiddlewareConstant, true)
+	ctx.Next()
+}
@@ -12,7 +12,7 @@ import (
 
 var log = logger.Log
 
-// GenerateSQLFile Generates a file in migrations/scripts/ directory in required migration format for a given tableName
+// GenerateSQLFile Generates an up and a down file in migrations/scripts/ directory in required migration format for a given tableName
 func GenerateSQLFile(tableName string) {
 	var sb strings.Builder
 	timeString := time.Now().Format("20060102150405.003059_")
@@ -21,21 +21,26 @@ func GenerateSQLFile(tableName string) {
 	sb.WriteString("../migrations/scripts/")
 	sb.WriteString(regexString.ReplaceAllString(timeString, replaceString))
 	sb.WriteString(tableName)
-	sb.WriteString(".sql")
-	fileName := sb.String()
-	fmt.Println(fileName)
-	emptyFile, err := os.Create(fileName)
-	emptyFile.Close()
-	if err != nil {
-		log.Panicln(err)
+	for _, suffix := range []string{".sql", ".down.sql"} {
+		fileName := sb.String() + suffix
+		fmt.Println(fileName)
+		emptyFile, err := os.Create(fileName)
+		emptyFile.Close()
+		if err != nil {
+			log.Panicln(err)
+		}
+		log.Println("Created SQL File:", fileName)
 	}
-	log.Println("Created SQL File:", fileName)
 }
 
 func main() {
 	if len(os.Args) <= 1 {
 		log.Fatal("Please specify a table name as first argument")
 	}
+	if os.Args[1] == "migrate" {
+		RunMigrate(os.Args[2:])
+		return
+	}
 	fileName := os.Args[1]
 	GenerateSQLFile(fileName)
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/migrate"
+)
+
+const migrationsDir = "../migrations/scripts"
+
+const migrateUsage = "usage: migrate status | up | down [steps] | to <version>"
+
+// RunMigrate runs a migrate subcommand against the database in DB_URL
+func RunMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	dbUrl := os.Getenv("DB_URL")
+	if dbUrl == "" {
+		log.Fatal("DB_URL is not set")
+	}
+	database, err := sqlx.Connect("postgres", dbUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	migrator := migrate.New(database, migrationsDir)
+
+	var changed []migrate.Migration
+	switch args[0] {
+	case "status":
+		printStatus(ctx, migrator)
+		return
+	case "up":
+		changed, err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatal("steps must be a positive number")
+			}
+		}
+		changed, err = migrator.Down(ctx, steps)
+	case "to":
+		if len(args) < 2 {
+			log.Fatal(migrateUsage)
+		}
+		changed, err = migrator.To(ctx, args[1])
+	default:
+		log.Fatal(migrateUsage)
+	}
+
+	// report partial progress before failing, earlier migrations stay applied
+	for _, migration := range changed {
+		fmt.Println(args[0], migration.Version, migration.Name)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(changed) == 0 {
+		fmt.Println("Nothing to migrate")
+	}
+}
+
+func printStatus(ctx context.Context, migrator *migrate.Migrator) {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, status := range statuses {
+		state := "pending"
+		switch {
+		case status.Missing:
+			state = "applied, file missing"
+		case status.Modified:
+			state = "applied, checksum mismatch"
+		case status.Applied:
+			state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%s  %-40s %s\n", status.Version, status.Name, state)
+	}
+}
@@ -6,10 +6,13 @@ import (
 	"github.com/swarajkumarsingh/turbo-deploy/constants"
 )
 
-var AWS_TOKEN string = os.Getenv("STAGE")
-var AWS_REGION string = os.Getenv("STAGE")
-var AWS_ACCESS_KEY string = os.Getenv("STAGE")
-var AWS_SECRET_ACCESS_KEY string = os.Getenv("STAGE")
+var AWS_TOKEN string = os.Getenv("AWS_TOKEN")
+var AWS_REGION string = os.Getenv("AWS_REGION")
+var AWS_ACCESS_KEY string = os.Getenv("AWS_ACCESS_KEY")
+var AWS_SECRET_ACCESS_KEY string = os.Getenv("AWS_SECRET_ACCESS_KEY")
+
+// AWS_SQS_ENDPOINT points the sqs client at a local emulator such as ElasticMQ or LocalStack when set
+var AWS_SQS_ENDPOINT string = os.Getenv("AWS_SQS_ENDPOINT")
 
 var ENV string = os.Getenv("STAGE")
 var VaultKey string = os.Getenv("VAULT_KEY")
@@ -30,4 +33,12 @@ const REGION = "ap-south-1"
 const DDServiceName = "go-deployable-kyc"
 
 // DDAgentHost is Hostname for Datadog agent
-var DDAgentHost string = "172.17.0.1"
\ No newline at end of file
+var DDAgentHost string = "172.17.0.1"
+
+// OutboxQueueUrl is the FIFO queue the outbox relay publishes to, OutboxRedisChannel an optional pub/sub channel,
+// the relay does not run when neither is set
+var OutboxQueueUrl = os.Getenv("OUTBOX_SQS_URL")
+var OutboxRedisChannel = os.Getenv("OUTBOX_REDIS_CHANNEL")
+
+// AdminApiKey guards the operational endpoints, they are disabled when it is empty
+var AdminApiKey = os.Getenv("ADMIN_API_KEY")
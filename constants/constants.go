@@ -1,6 +1,9 @@
 package constants
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 var STAGE string = os.Getenv("STAGE")
 
@@ -15,11 +18,56 @@ const (
 const DefaultRateLimiterPerMinute = 10
 
 const DefaultPerPageSize = 10
+const MaxPageLimit = 100
+const MaxSearchQueryLength = 200
+const DefaultAnalyticsRange = 7 * 24 * time.Hour
+const MaxAnalyticsRange = 90 * 24 * time.Hour
+const DefaultAnalyticsTopPaths = 10
+const DefaultAuditRange = 30 * 24 * time.Hour
 const DefaultPageSize = 10
+
+// Deleted projects and deployments stay restorable for TrashRetention, the purge job removes them afterwards
+const TrashRetention = 30 * 24 * time.Hour
+const TrashPurgeInterval = time.Hour
+const TrashPurgeBatchSize = 100
+
+// Build outputs outside a project's retention policy are collected every ArtifactGCInterval
+const ArtifactGCInterval = 6 * time.Hour
+const ArtifactGCBatchSize = 100
 const BcryptHashingCost = 8
 
+// MaxMaintenancePageSize bounds the maintenance page html in bytes
+const MaxMaintenancePageSize = 64 << 10
+
+// ChannelVerificationTTL is how long the code sent to a new email channel can be used
+const ChannelVerificationTTL = 24 * time.Hour
+
+// Project build settings, see models/project.BuildSettings
+const MaxBuildCommandLength = 512
+const MaxBuildDirLength = 255
+
+var SupportedRuntimeVersions = []string{"18", "20", "22"}
+
+// GithubApiToken is optional, it raises the GitHub API rate limit of framework detection
+var GithubApiToken string = os.Getenv("GITHUB_TOKEN")
+
+const FrameworkDetectionTimeout = 10 * time.Second
+
+// Direct upload deployments, MaxUploadSize bounds the request and the others what the archive extracts to
+const MaxUploadSize = 100 << 20
+const MaxUploadExtractedSize = 500 << 20
+const MaxUploadFiles = 10000
+const UploadDeployTimeout = 15 * time.Minute
+const SourceArchiveUrlTTL = time.Hour
+
+// Commit metadata of a deployment, longer values from the create request are rejected
+const MaxCommitMessageLength = 4096
+const MaxCommitAuthorLength = 255
+
 const VaultKeySuffix = "-vlt"
 const UserIdMiddlewareConstant = "userId"
+const AdminMiddlewareConstant = "isAdmin"
+const RequestIdMiddlewareConstant = "requestId"
 
 const DefaultSenderEmailId = "swaraj.singh.wearingo@gmail.com"
 const DefaultRecipientEmailId = "swaraj.singh.wearingo@gmail.com"
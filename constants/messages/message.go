@@ -31,4 +31,57 @@ const (
 	FailedToRetrieveDeploymentLogsMessage = "failed to deployment logs"
 	FailedToRetrieveFavoritesMessage      = "failed to retrieve favorites"
 	FailedToRetrieveSellersMessage        = "failed to retrieve sellers"
+	ProjectAccessNotFoundMessage          = "project access controls not configured"
+	ProjectForbiddenMessage               = "you do not have access to this project"
+	InvalidAllowedCIDRMessage             = "invalid cidr in allowed_cidrs"
+	AccessCredentialsRequiredMessage      = "username and password are required for this access mode"
+	InvalidTimeRangeMessage               = "invalid time range"
+	FailedToRetrieveAnalyticsMessage      = "failed to retrieve analytics"
+	InvalidQuarantineIdMessage            = "invalid quarantined message id"
+	InvalidQuarantineStatusMessage        = "invalid quarantine status"
+	QuarantinedMessageNotFoundMessage     = "quarantined message not found"
+	QuarantinedMessageRedrivenMessage     = "message was already redriven"
+	FailedToRetrieveQuarantineMessage     = "failed to retrieve quarantined messages"
+	FailedToRedriveMessage                = "failed to redrive message"
+	FailedToRetrievePreferencesMessage    = "failed to retrieve notification preferences"
+	InvalidWebhookIdMessage               = "invalid webhook id"
+	InvalidWebhookDeliveryIdMessage       = "invalid webhook delivery id"
+	InvalidWebhookEventMessage            = "invalid webhook event"
+	InvalidWebhookURLMessage              = "webhook url must use http or https"
+	WebhookURLNotPublicMessage            = "webhook url must resolve to a public address"
+	WebhookNotFoundMessage                = "webhook not found"
+	WebhookDeliveryNotFoundMessage        = "webhook delivery not found"
+	FailedToRetrieveWebhooksMessage       = "failed to retrieve webhooks"
+	FailedToRetrieveDeliveriesMessage     = "failed to retrieve webhook deliveries"
+	InvalidChannelIdMessage               = "invalid notification channel id"
+	InvalidChannelTargetMessage           = "target must be an http or https url, or an email address for email channels"
+	ChannelNotFoundMessage                = "notification channel not found"
+	FailedToRetrieveChannelsMessage       = "failed to retrieve notification channels"
+	ChannelNotVerifiedMessage             = "verify the email channel before activating it"
+	ChannelAlreadyVerifiedMessage         = "only unverified email channels can be verified"
+	InvalidVerificationCodeMessage        = "invalid or expired verification code"
+	ProjectNotInTrashMessage              = "project is not in the trash or its retention window expired"
+	FailedToRetrieveAuditEventsMessage    = "failed to retrieve audit events"
+	InvalidCursorMessage                  = "invalid cursor"
+	InvalidLimitMessage                   = "limit must be between 1 and 100"
+	InvalidDeploymentStatusMessage        = "status must be one of QUEUE, PROG, READY, FAIL"
+	InvalidLogTypeMessage                 = "log_type must be one of INFO, WARN, ERROR"
+	InvalidSearchQueryMessage             = "q is required and must be at most 200 characters"
+	FailedToSearchDeploymentLogsMessage   = "failed to search deployment logs"
+	InvalidBuildCommandMessage            = "install_command and build_command must be npm, npx, yarn or pnpm commands joined by && and at most 512 characters"
+	InvalidBuildDirMessage                = "output_dir and root_dir must be relative paths inside the repository"
+	InvalidRuntimeVersionMessage          = "runtime_version must be one of 18, 20, 22"
+	FailedToDetectFrameworkMessage        = "failed to read the repository to detect its framework"
+	InvalidUploadKindMessage              = "kind must be static or source"
+	InvalidArchiveMessage                 = "file must be a zip or tar.gz archive"
+	UploadTooLargeMessage                 = "upload must be at most 100MB"
+	UploadNotDeployableMessage            = "static uploads need an index.html and source uploads a package.json at the archive root"
+	InvalidBranchMessage                  = "invalid branch name"
+	DeploymentNotRedeployableMessage      = "only git deployments with a recorded commit and source uploads can be redeployed"
+	DeploymentArtifactsDeletedMessage     = "the outputs of this deployment were removed by the project retention policy"
+	DeploymentNotPromotableMessage        = "only READY deployments can be promoted"
+	InvalidCommitShaMessage               = "commit sha must be hexadecimal, the full 40 characters when creating a deployment"
+	InvalidCommitMetadataMessage          = "commit message must be at most 4096 and commit author at most 255 characters"
+	InvalidTriggerSourceMessage           = "trigger must be one of manual, webhook, api_token, redeploy"
+	MaintenancePageTooLargeMessage        = "maintenance page must be at most 64KB"
 )
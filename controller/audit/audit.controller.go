@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
+	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	model "github.com/swarajkumarsingh/turbo-deploy/models/audit"
+)
+
+// list audit events, optionally filtered by resource, actor, action and time range
+func GetAuditEvents(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	page := getCurrentPageValue(ctx)
+	itemsPerPage := getItemPerPageValue(ctx)
+	offset := getOffsetValue(page, itemsPerPage)
+
+	filter, err := getAuditFilter(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+
+	events, err := model.GetAuditEvents(reqCtx, filter, itemsPerPage, offset)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveAuditEventsMessage)
+	}
+
+	total, err := model.GetTotalAuditEventsCount(reqCtx, filter)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveAuditEventsMessage)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":       false,
+		"events":      events,
+		"from":        filter.From,
+		"to":          filter.To,
+		"page":        page,
+		"per_page":    itemsPerPage,
+		"total":       total,
+		"total_pages": calculateTotalPages(total, itemsPerPage),
+	})
+}
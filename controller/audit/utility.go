@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
+	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	model "github.com/swarajkumarsingh/turbo-deploy/models/audit"
+)
+
+// getAuditFilter reads the filters from the query, the time range defaults to the last DefaultAuditRange
+func getAuditFilter(ctx *gin.Context) (model.AuditFilter, error) {
+	filter := model.AuditFilter{
+		ResourceType: ctx.Query("resource_type"),
+		ResourceId:   ctx.Query("resource_id"),
+		ActorId:      ctx.Query("actor_id"),
+		Action:       ctx.Query("action"),
+		To:           time.Now().UTC(),
+	}
+
+	if value := ctx.Query("to"); value != "" {
+		parsed, err := parseAuditTime(value)
+		if err != nil {
+			return filter, errors.New(messages.InvalidTimeRangeMessage)
+		}
+		filter.To = parsed
+	}
+	filter.From = filter.To.Add(-constants.DefaultAuditRange)
+	if value := ctx.Query("from"); value != "" {
+		parsed, err := parseAuditTime(value)
+		if err != nil {
+			return filter, errors.New(messages.InvalidTimeRangeMessage)
+		}
+		filter.From = parsed
+	}
+
+	if !filter.From.Before(filter.To) {
+		return filter, errors.New(messages.InvalidTimeRangeMessage)
+	}
+	return filter, nil
+}
+
+func parseAuditTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.UTC(), nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+func getCurrentPageValue(ctx *gin.Context) int {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		logger.WithRequest(ctx).Errorln("Invalid page value; defaulting to 1:", err)
+		return 1
+	}
+	return page
+}
+
+func getItemPerPageValue(ctx *gin.Context) int {
+	perPage, err := strconv.Atoi(ctx.DefaultQuery("per_page", strconv.Itoa(constants.DefaultPerPageSize)))
+	if err != nil || perPage <= 0 || perPage > 100 {
+		logger.WithRequest(ctx).Errorln("Invalid per_page value; defaulting to:", constants.DefaultPerPageSize)
+		return constants.DefaultPerPageSize
+	}
+	return perPage
+}
+
+func getOffsetValue(page int, itemsPerPage int) int {
+	return (page - 1) * itemsPerPage
+}
+
+func calculateTotalPages(total, itemsPerPage int) int {
+	if itemsPerPage <= 0 {
+		return 1
+	}
+	return (total + itemsPerPage - 1) / itemsPerPage
+}
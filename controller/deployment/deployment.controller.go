@@ -1,14 +1,19 @@
 package deployment
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
 	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
 	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/audit"
 	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
-	"github.com/swarajkumarsingh/turbo-deploy/infra/db"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/pagination"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/artifacts"
 	model "github.com/swarajkumarsingh/turbo-deploy/models/deployment"
 )
 
@@ -34,31 +39,22 @@ func CreateDeployment(ctx *gin.Context) {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, "deployment already queued")
 	}
 
-	var database = db.Mgr.DBConn
-	tx, err := database.BeginTx(reqCtx, nil)
-	if err != nil {
-		logger.Log.Errorln(err)
-		logger.WithRequest(ctx).Panicln("error starting transaction")
-	}
-
-	deploymentId, err := model.CreateDeploymentTx(reqCtx, tx, project.Id, project.UserId)
-	if err != nil {
-		logger.Log.Errorln(err)
-		_ = tx.Rollback()
-		logger.WithRequest(ctx).Panicln("error while creating deployment")
-	}
-
-	_, err = spinEcsTask(reqCtx, deploymentId, project)
-	if err != nil {
-		logger.Log.Errorln(err)
-		_ = tx.Rollback()
-		logger.WithRequest(ctx).Panicln("error while launching container")
-	}
-
-	if err := tx.Commit(); err != nil {
-		logger.Log.Errorln(err)
-		logger.WithRequest(ctx).Panicln("error while committing transaction")
+	spec := model.DeploymentSpec{
+		Source:        model.SourceGit,
+		Target:        body.Target,
+		Branch:        body.Branch,
+		CommitSha:     body.CommitSha,
+		CommitMessage: body.CommitMessage,
+		CommitAuthor:  body.CommitAuthor,
+		Trigger:       model.TriggerManual,
+		Settings:      project.BuildSettings,
 	}
+	deploymentId := queueDeployment(ctx, project, spec, func(reqCtx context.Context, deploymentId int) error {
+		_, err := spinEcsTask(reqCtx, deploymentId, project, buildOptions{Branch: body.Branch, CommitSha: body.CommitSha})
+		return err
+	})
+	audit.Record(ctx, "deployment.create", "deployment", deploymentId, nil,
+		gin.H{"project_id": project.Id, "status": "QUEUE", "target": body.Target, "trigger_source": model.TriggerManual})
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"error":  false,
@@ -111,37 +107,49 @@ func GetAllDeployment(ctx *gin.Context) {
 	defer errorHandler.Recovery(ctx, http.StatusConflict)
 	reqCtx := ctx.Request.Context()
 
-	page := getCurrentPageValue(ctx)
-	itemsPerPage := getItemPerPageValue(ctx)
-	offset := getOffsetValue(page, itemsPerPage)
+	params, err := pagination.GetParams(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+
+	filter, err := getDeploymentFilter(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
 
 	userId, valid := getUserIdFromReq(ctx)
 	if !valid {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
 	}
 
-	rows, err := model.GetDeploymentListPaginatedValue(reqCtx, userId, itemsPerPage, offset)
+	rows, err := model.GetDeploymentListAfter(reqCtx, userId, filter, params.After, params.FetchLimit())
 	if err != nil {
 		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveProjectsMessage)
 	}
 	defer rows.Close()
 
 	deployments := make([]gin.H, 0)
+	fetched, lastId := 0, int64(0)
 
 	for rows.Next() {
-		var id int
-		var projectId, status, readyUrl string
-		if err := rows.Scan(&id, &projectId, &status, &readyUrl); err != nil {
+		if fetched++; fetched > params.Limit {
+			break
+		}
+		var id int64
+		var projectId, status, readyUrl, commitSha, branch, commitMessage, commitAuthor, triggerSource string
+		if err := rows.Scan(&id, &projectId, &status, &readyUrl, &commitSha, &branch, &commitMessage, &commitAuthor, &triggerSource); err != nil {
 			logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveProjectsMessage)
 		}
-		deployments = append(deployments, gin.H{"id": id, "projectId": projectId, "status": status, "readyUrl": readyUrl})
+		deployments = append(deployments, gin.H{"id": id, "projectId": projectId, "status": status, "readyUrl": readyUrl,
+			"commitSha": commitSha, "branch": branch, "commitMessage": commitMessage, "commitAuthor": commitAuthor,
+			"triggerSource": triggerSource})
+		lastId = id
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"deployments": deployments,
-		"page":        page,
-		"per_page":    itemsPerPage,
-		"total_pages": calculateTotalPages(page, itemsPerPage),
+		"limit":       params.Limit,
+		"next_cursor": params.NextCursor(fetched, lastId),
 	})
 }
 
@@ -154,13 +162,16 @@ func DeleteDeployment(ctx *gin.Context) {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
 	}
 
-	if err := model.DeleteDeploymentFromUser(reqCtx, id); err != nil {
-		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	deployment, err := model.GetDeploymentById(reqCtx, id)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.DeploymentNotFoundMessage)
 	}
 
-	if err := deleteS3FilesForDeployment(id); err != nil {
-		logger.WithRequest(ctx).Errorln("Failed to delete S3 files for deployment:", id, err)
+	// build outputs are removed by the purge job once the retention window expired
+	if err := model.DeleteDeploymentFromUser(reqCtx, id); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
 	}
+	audit.Record(ctx, "deployment.delete", "deployment", id, deployment, nil)
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"error":   false,
@@ -177,23 +188,120 @@ func DeleteAllDeployment(ctx *gin.Context) {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
 	}
 
-	deploymentIDs, err := model.GetAllDeploymentIDsByUser(reqCtx, uid)
+	if err := model.DeleteAllDeploymentFromUser(reqCtx, uid); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+	audit.Record(ctx, "deployment.delete_all", "user", uid, nil, nil)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":   false,
+		"message": "all deployment deleted successfully",
+	})
+}
+
+// rebuild the commit, or uploaded source, and build settings of a deployment as a new deployment
+func RedeployDeployment(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	deployment := getOwnedDeployment(ctx)
+
+	// the body is optional
+	var body model.RedeployBody
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidBodyMessage)
+		}
+	}
+
+	switch {
+	case deployment.Source == model.SourceGit && deployment.CommitSha == "":
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.DeploymentNotRedeployableMessage)
+	case deployment.Source == model.SourceStaticUpload:
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.DeploymentNotRedeployableMessage)
+	case deployment.Source == model.SourceSourceUpload && deployment.ArtifactsDeletedAt != nil:
+		logger.WithRequest(ctx).Panicln(http.StatusConflict, messages.DeploymentArtifactsDeletedMessage)
+	}
+
+	project, err := model.GetProjectById(reqCtx, deployment.ProjectId)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.ProjectNotFoundMessage)
+	}
+
+	exists, err := deploymentAlreadyQueued(reqCtx, project.Id)
 	if err != nil {
-		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, "Failed to fetch deployment IDs")
+		logger.WithRequest(ctx).Panicln(messages.SomethingWentWrongMessage)
+	}
+	if exists {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, "deployment already queued")
 	}
 
-	for _, deploymentID := range deploymentIDs {
-		if err := deleteS3FilesForDeployment(deploymentID); err != nil {
-			logger.WithRequest(ctx).Errorln("Failed to delete S3 files for deployment:", deploymentID, err)
+	// deployments from before settings were recorded are rebuilt with the current project settings
+	if snapshot := deployment.Settings.String(); snapshot != "" && snapshot != "{}" {
+		if err := json.Unmarshal(deployment.Settings, &project.BuildSettings); err != nil {
+			logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
 		}
 	}
 
-	if err := model.DeleteAllDeploymentFromUser(reqCtx, uid); err != nil {
+	spec := model.DeploymentSpec{
+		Source:        deployment.Source,
+		Target:        deployment.Target,
+		Branch:        deployment.Branch,
+		CommitSha:     deployment.CommitSha,
+		CommitMessage: deployment.CommitMessage,
+		CommitAuthor:  deployment.CommitAuthor,
+		Trigger:       model.TriggerRedeploy,
+		Settings:      project.BuildSettings,
+		RedeployOf:    &deployment.Id,
+	}
+	deploymentId := queueDeployment(ctx, project, spec, func(reqCtx context.Context, deploymentId int) error {
+		build := buildOptions{Branch: deployment.Branch, CommitSha: deployment.CommitSha, ClearCache: body.ClearCache}
+		if deployment.Source == model.SourceSourceUpload {
+			url, err := artifacts.CopySourceArchive(reqCtx, deployment.Id, deploymentId, constants.SourceArchiveUrlTTL)
+			if err != nil {
+				return err
+			}
+			build = buildOptions{SourceArchiveUrl: url, ClearCache: body.ClearCache}
+		}
+		_, err := spinEcsTask(reqCtx, deploymentId, project, build)
+		return err
+	})
+	audit.Record(ctx, "deployment.redeploy", "deployment", deploymentId, nil,
+		gin.H{"project_id": project.Id, "status": "QUEUE", "redeploy_of": deployment.Id, "clear_cache": body.ClearCache})
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":  false,
+		"status": "queued",
+		"data":   gin.H{"deploymentId": deploymentId, "redeployOf": deployment.Id},
+	})
+}
+
+// serve a READY deployment, typically a preview, as the production deployment of its project without rebuilding
+func PromoteDeployment(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	deployment := getOwnedDeployment(ctx)
+	if deployment.ArtifactsDeletedAt != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusConflict, messages.DeploymentArtifactsDeletedMessage)
+	}
+	if deployment.Status != "READY" {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.DeploymentNotPromotableMessage)
+	}
+
+	promoted, err := model.PromoteDeployment(reqCtx, deployment.Id)
+	if err != nil {
 		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
 	}
+	if !promoted {
+		logger.WithRequest(ctx).Panicln(http.StatusConflict, messages.DeploymentNotPromotableMessage)
+	}
+	if updated, err := model.GetDeploymentById(reqCtx, deployment.Id); err == nil {
+		audit.Record(ctx, "deployment.promote", "deployment", deployment.Id, deployment, updated)
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"error":   false,
-		"message": "all deployment deleted successfully",
+		"message": fmt.Sprintf("%d deployment promoted to production", deployment.Id),
 	})
 }
@@ -0,0 +1,268 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
+	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
+	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/archive"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/audit"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/artifacts"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs"
+	model "github.com/swarajkumarsingh/turbo-deploy/models/deployment"
+	projectModel "github.com/swarajkumarsingh/turbo-deploy/models/project"
+)
+
+const (
+	uploadKindStatic = "static"
+	uploadKindSource = "source"
+)
+
+// deploy a zip or tar.gz upload, either prebuilt static files which are deployed as they are or
+// source which is built by the runner like a repository
+func UploadDeployment(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	project := getOwnedProject(ctx)
+
+	exists, err := deploymentAlreadyQueued(reqCtx, project.Id)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(messages.SomethingWentWrongMessage)
+	}
+	if exists {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, "deployment already queued")
+	}
+
+	kind := strings.ToLower(ctx.PostForm("kind"))
+	if kind != "" && kind != uploadKindStatic && kind != uploadKindSource {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUploadKindMessage)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "upload-")
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+	// the static deploy goroutine takes over the directory once the deployment is queued
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			os.RemoveAll(tmpDir)
+		}
+	}()
+
+	root := extractUpload(ctx, tmpDir)
+
+	hasPackageJson := fileExists(filepath.Join(root, "package.json"))
+	if kind == "" {
+		kind = uploadKindStatic
+		if hasPackageJson {
+			kind = uploadKindSource
+		}
+	}
+	if (kind == uploadKindSource && !hasPackageJson) || (kind == uploadKindStatic && !fileExists(filepath.Join(root, "index.html"))) {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.UploadNotDeployableMessage)
+	}
+
+	var launch func(reqCtx context.Context, deploymentId int) error
+	if kind == uploadKindSource {
+		launch = func(reqCtx context.Context, deploymentId int) error {
+			url, err := uploadSource(reqCtx, deploymentId, root, tmpDir)
+			if err != nil {
+				return err
+			}
+			_, err = spinEcsTask(reqCtx, deploymentId, project, buildOptions{SourceArchiveUrl: url})
+			return err
+		}
+	}
+
+	spec := model.DeploymentSpec{
+		Source:   model.SourceStaticUpload,
+		Target:   model.TargetProduction,
+		Trigger:  model.TriggerManual,
+		Settings: project.BuildSettings,
+	}
+	if kind == uploadKindSource {
+		spec.Source = model.SourceSourceUpload
+	}
+	deploymentId := queueDeployment(ctx, project, spec, launch)
+	if kind == uploadKindStatic {
+		handedOff = true
+		go deployStaticUpload(deploymentId, project, root, tmpDir)
+	}
+	audit.Record(ctx, "deployment.create", "deployment", deploymentId, nil, gin.H{"project_id": project.Id, "status": "QUEUE", "upload": kind})
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":  false,
+		"status": "queued",
+		"data":   gin.H{"deploymentId": deploymentId, "kind": kind},
+	})
+}
+
+// getOwnedProject loads the project from the :pid param and panics unless it belongs to the authorized user
+func getOwnedProject(ctx *gin.Context) projectModel.Project {
+	userId, valid := getUserIdFromReq(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
+	}
+
+	pid := ctx.Param("pid")
+	if _, err := strconv.Atoi(pid); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidProjectIdMessage)
+	}
+
+	project, err := model.GetProjectById(ctx.Request.Context(), pid)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.ProjectNotFoundMessage)
+	}
+	if project.UserId != userId {
+		logger.WithRequest(ctx).Panicln(http.StatusForbidden, messages.ProjectForbiddenMessage)
+	}
+	return project
+}
+
+// extractUpload extracts the file form field into dir and returns the directory its content starts at
+func extractUpload(ctx *gin.Context, dir string) string {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, constants.MaxUploadSize)
+
+	file, header, err := ctx.Request.FormFile("file")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			logger.WithRequest(ctx).Panicln(http.StatusRequestEntityTooLarge, messages.UploadTooLargeMessage)
+		}
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidArchiveMessage)
+	}
+	defer file.Close()
+
+	format := archive.Format(header.Filename)
+	if format == "" {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidArchiveMessage)
+	}
+
+	extractDir := filepath.Join(dir, "files")
+	err = archive.Extract(file, header.Size, format, extractDir, archive.Limits{
+		MaxFiles: constants.MaxUploadFiles,
+		MaxBytes: constants.MaxUploadExtractedSize,
+	})
+	if errors.Is(err, archive.ErrTooLarge) || errors.Is(err, archive.ErrTooManyFiles) {
+		logger.WithRequest(ctx).Panicln(http.StatusRequestEntityTooLarge, err)
+	}
+	if errors.Is(err, archive.ErrUnsafePath) || errors.Is(err, archive.ErrUnsupportedFormat) {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(header.Filename, err.Error())
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidArchiveMessage)
+	}
+
+	root, err := archive.Root(extractDir)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidArchiveMessage)
+	}
+	return root
+}
+
+// uploadSource repacks the extracted source, so only entries that passed the extraction checks reach
+// the builder, and returns the url the builder downloads it from
+func uploadSource(ctx context.Context, deploymentId int, root, tmpDir string) (string, error) {
+	packed, err := os.Create(filepath.Join(tmpDir, "source.tar.gz"))
+	if err != nil {
+		return "", err
+	}
+	defer packed.Close()
+
+	if err := archive.WriteTarGz(root, packed); err != nil {
+		return "", err
+	}
+	if _, err := packed.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return artifacts.UploadSourceArchive(ctx, deploymentId, packed, constants.SourceArchiveUrlTTL)
+}
+
+// deployStaticUpload uploads prebuilt files as the outputs of the deployment and reports progress through
+// the log and status queues like the build server does, so the deployment goes through the same consumers
+func deployStaticUpload(deploymentId int, project projectModel.Project, root, tmpDir string) {
+	defer os.RemoveAll(tmpDir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.UploadDeployTimeout)
+	defer cancel()
+
+	reporter := uploadReporter{deploymentId: deploymentId, projectId: project.Id}
+	reporter.status("PROG")
+	reporter.log("INFO", "Deploying uploaded static files")
+
+	count, err := artifacts.UploadDeploymentOutputs(ctx, deploymentId, root, isHiddenPath, func(relative string) {
+		reporter.log("INFO", "Uploaded "+relative)
+	})
+	if err != nil {
+		logger.Log.Errorln(fmt.Sprintf("static upload of deployment %d failed: %v", deploymentId, err))
+		reporter.log("ERROR", "Deployment failed: "+err.Error())
+		reporter.status("FAIL")
+		return
+	}
+
+	reporter.log("INFO", fmt.Sprintf("All %d files uploaded successfully.", count))
+	reporter.status("READY")
+}
+
+// uploadReporter publishes messages in the format of the build server
+type uploadReporter struct {
+	deploymentId int
+	projectId    int
+}
+
+func (r uploadReporter) status(status string) {
+	r.publish(constants.TaskDefinitionStatusQueueUrl, gin.H{"status": status})
+}
+
+func (r uploadReporter) log(logType, message string) {
+	r.publish(constants.TaskDefinitionLogQueueUrl, gin.H{"message": message, "logType": logType, "environment": constants.ENV_DEV})
+}
+
+func (r uploadReporter) publish(queueUrl string, fields gin.H) {
+	host, _ := os.Hostname()
+	fields["appName"] = constants.TaskDefinitionENVAppName
+	fields["projectId"] = strconv.Itoa(r.projectId)
+	fields["deploymentId"] = strconv.Itoa(r.deploymentId)
+	fields["host"] = host
+	fields["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+	if err := sqs.SendMessage(string(body), queueUrl); err != nil {
+		logger.Log.Errorln(fmt.Sprintf("failed to publish upload progress of deployment %d: %v", r.deploymentId, err))
+	}
+}
+
+// isHiddenPath reports whether a file is under a dot folder or is a dot file, like .git or .env,
+// except for .well-known
+func isHiddenPath(relative string) bool {
+	for _, segment := range strings.Split(relative, "/") {
+		if strings.HasPrefix(segment, ".") && segment != ".well-known" {
+			return true
+		}
+	}
+	return false
+}
+
+func fileExists(file string) bool {
+	info, err := os.Stat(file)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -4,13 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
@@ -21,22 +22,12 @@ import (
 	"github.com/swarajkumarsingh/turbo-deploy/functions/general"
 	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
 	validators "github.com/swarajkumarsingh/turbo-deploy/functions/validator"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/db"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/outbox"
 	model "github.com/swarajkumarsingh/turbo-deploy/models/deployment"
 	projectModel "github.com/swarajkumarsingh/turbo-deploy/models/project"
 )
 
-func getS3Client() *s3.Client {
-	cfg, err := config.LoadDefaultConfig(
-		context.TODO(),
-		config.WithRegion(constants.AWS_REGION),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(constants.AWS_ACCESS_KEY_ID, constants.AWS_SECRET_ACCESS_KEY, "")),
-	)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to load AWS configuration: %v", err))
-	}
-	return s3.NewFromConfig(cfg)
-}
-
 func getCreateDeploymentBody(ctx *gin.Context) (model.DeploymentBody, error) {
 	var body model.DeploymentBody
 	if err := ctx.ShouldBindJSON(&body); err != nil {
@@ -47,9 +38,56 @@ func getCreateDeploymentBody(ctx *gin.Context) (model.DeploymentBody, error) {
 		return body, err
 	}
 
+	if body.Branch != "" && !isValidBranch(body.Branch) {
+		return body, errors.New(messages.InvalidBranchMessage)
+	}
+	if body.Target == "" {
+		body.Target = model.TargetProduction
+	}
+
+	body.CommitSha = strings.ToLower(body.CommitSha)
+	if body.CommitSha != "" && !fullCommitShaPattern.MatchString(body.CommitSha) {
+		return body, errors.New(messages.InvalidCommitShaMessage)
+	}
+	if len(body.CommitMessage) > constants.MaxCommitMessageLength || len(body.CommitAuthor) > constants.MaxCommitAuthorLength {
+		return body, errors.New(messages.InvalidCommitMetadataMessage)
+	}
+
 	return body, nil
 }
 
+var (
+	fullCommitShaPattern   = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	commitShaPrefixPattern = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+)
+
+var branchPattern = regexp.MustCompile(`^[A-Za-z0-9._/-]{1,255}$`)
+
+// isValidBranch accepts the branch names git allows that are safe to hand to the builder
+func isValidBranch(branch string) bool {
+	return branchPattern.MatchString(branch) && !strings.HasPrefix(branch, "-") && !strings.Contains(branch, "..") &&
+		!strings.HasSuffix(branch, ".lock") && !strings.HasSuffix(branch, "/")
+}
+
+// getOwnedDeployment loads the deployment from the :id param and panics unless it belongs to the authorized user
+func getOwnedDeployment(ctx *gin.Context) model.Deployment {
+	id, valid := getDeploymentIdFromParam(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidDeploymentIdMessage)
+	}
+
+	userId, valid := getUserIdFromReq(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
+	}
+
+	deployment, err := model.GetDeploymentById(ctx.Request.Context(), id)
+	if err != nil || deployment.UserId != userId {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.DeploymentNotFoundMessage)
+	}
+	return deployment
+}
+
 func deploymentAlreadyQueued(context context.Context, projectId int) (bool, error) {
 	count, err := model.GetQueuedProjectCount(context, projectId)
 	if err != nil || count > 0 {
@@ -73,8 +111,65 @@ func getDeploymentIdFromParam(ctx *gin.Context) (int, bool) {
 	return uid, true
 }
 
+// queueDeployment creates a QUEUE deployment of the project together with its outbox event and runs launch
+// in the same transaction, so a deployment whose build could not be started is never committed
+func queueDeployment(ctx *gin.Context, project projectModel.Project, spec model.DeploymentSpec,
+	launch func(reqCtx context.Context, deploymentId int) error) int {
+	reqCtx := ctx.Request.Context()
+
+	var database = db.Mgr.DBConn
+	tx, err := database.BeginTx(reqCtx, nil)
+	if err != nil {
+		logger.Log.Errorln(err)
+		logger.WithRequest(ctx).Panicln("error starting transaction")
+	}
+
+	deploymentId, err := model.CreateDeploymentTx(reqCtx, tx, project.Id, project.UserId, spec)
+	if err != nil {
+		logger.Log.Errorln(err)
+		_ = tx.Rollback()
+		logger.WithRequest(ctx).Panicln("error while creating deployment")
+	}
+
+	err = outbox.Insert(reqCtx, tx, outbox.Message{
+		AggregateType: outbox.AggregateDeployment,
+		AggregateId:   strconv.Itoa(deploymentId),
+		EventType:     outbox.EventDeploymentQueued,
+		DedupId:       outbox.DeploymentDedupId(deploymentId, "QUEUE"),
+		Payload:       gin.H{"deployment_id": deploymentId, "project_id": project.Id, "user_id": project.UserId, "status": "QUEUE"},
+	})
+	if err != nil {
+		logger.Log.Errorln(err)
+		_ = tx.Rollback()
+		logger.WithRequest(ctx).Panicln("error while creating deployment")
+	}
+
+	if launch != nil {
+		if err := launch(reqCtx, deploymentId); err != nil {
+			logger.Log.Errorln(err)
+			_ = tx.Rollback()
+			logger.WithRequest(ctx).Panicln("error while launching container")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Log.Errorln(err)
+		logger.WithRequest(ctx).Panicln("error while committing transaction")
+	}
+	return deploymentId
+}
+
+// buildOptions change what a build task builds, zero values build the default branch of the repository
+type buildOptions struct {
+	// SourceArchiveUrl is downloaded instead of cloning the repository, see UploadDeployment
+	SourceArchiveUrl string
+	Branch           string
+	CommitSha        string
+	ClearCache       bool
+}
+
 // spinEcsTask launches an ECS task
-func spinEcsTask(ctx context.Context, deploymentId int, project projectModel.Project) (string, error) {
+func spinEcsTask(ctx context.Context, deploymentId int, project projectModel.Project, build buildOptions) (string, error) {
 	// Load AWS config
 	cfg, err := config.LoadDefaultConfig(ctx,
 		config.WithCredentialsProvider(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
@@ -110,7 +205,6 @@ func spinEcsTask(ctx context.Context, deploymentId int, project projectModel.Pro
 						{Name: aws.String("PROJECT_ID"), Value: aws.String(fmt.Sprint(project.Id))},
 						{Name: aws.String("DEPLOYMENT_ID"), Value: aws.String(fmt.Sprint(deploymentId))},
 						{Name: aws.String("EMAIl_QUEUE_URL"), Value: aws.String("https://sqs.ap-south-1.amazonaws.com/491085393011/turbo-deploy-email-queue")},
-						{Name: aws.String("RECIPIENT_EMAIL"), Value: aws.String(project.UserId)},
 						{Name: aws.String("LOG_QUEUE_URL"), Value: aws.String(constants.TaskDefinitionLogQueueUrl)},
 						{Name: aws.String("STATUS_QUEUE_URL"), Value: aws.String(constants.TaskDefinitionStatusQueueUrl)},
 						{Name: aws.String("S3_BUCKET_NAME"), Value: aws.String(constants.TaskDefinitionS3BucketName)},
@@ -118,6 +212,15 @@ func spinEcsTask(ctx context.Context, deploymentId int, project projectModel.Pro
 						{Name: aws.String("AWS_ACCESS_KEY_ID"), Value: aws.String(constants.AWS_ACCESS_KEY_ID)},
 						{Name: aws.String("AWS_SECRET_ACCESS_KEY"), Value: aws.String(constants.AWS_SECRET_ACCESS_KEY)},
 						{Name: aws.String("GIT_REPOSITORY_URL"), Value: aws.String(project.SourceCodeUrl)},
+						{Name: aws.String("INSTALL_COMMAND"), Value: aws.String(project.InstallCommand)},
+						{Name: aws.String("BUILD_COMMAND"), Value: aws.String(project.BuildCommand)},
+						{Name: aws.String("OUTPUT_DIR"), Value: aws.String(project.OutputDir)},
+						{Name: aws.String("ROOT_DIR"), Value: aws.String(project.RootDir)},
+						{Name: aws.String("RUNTIME_VERSION"), Value: aws.String(project.RuntimeVersion)},
+						{Name: aws.String("SOURCE_ARCHIVE_URL"), Value: aws.String(build.SourceArchiveUrl)},
+						{Name: aws.String("GIT_BRANCH"), Value: aws.String(build.Branch)},
+						{Name: aws.String("COMMIT_SHA"), Value: aws.String(build.CommitSha)},
+						{Name: aws.String("CLEAR_BUILD_CACHE"), Value: aws.String(strconv.FormatBool(build.ClearCache))},
 					},
 				},
 			},
@@ -155,70 +258,60 @@ func getUserIdFromReq(ctx *gin.Context) (string, bool) {
 	return fmt.Sprintf("%v", uid), true
 }
 
-func getCurrentPageValue(ctx *gin.Context) int {
-	val, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	if err != nil {
-		logger.WithRequest(ctx).Errorln("error while extracting current page value: ", err)
-		return 1
+// getDeploymentFilter reads project_id, status, the created_at range from and to, branch, a commit_sha prefix
+// and trigger from the query
+func getDeploymentFilter(ctx *gin.Context) (model.DeploymentFilter, error) {
+	var filter model.DeploymentFilter
+
+	if value := ctx.Query("project_id"); value != "" {
+		projectId, err := strconv.Atoi(value)
+		if err != nil || projectId <= 0 {
+			return filter, errors.New(messages.InvalidProjectIdMessage)
+		}
+		filter.ProjectId = projectId
 	}
-	return val
-}
 
-func getOffsetValue(page int, itemsPerPage int) int {
-	return (page - 1) * itemsPerPage
-}
-func deleteS3FilesForDeployment(deploymentID int) error {
-	s3Client := getS3Client()
-	bucketName := constants.TaskDefinitionS3BucketName
-	prefix := fmt.Sprintf("__outputs/%d/", deploymentID)
+	switch status := strings.ToUpper(ctx.Query("status")); status {
+	case "", "QUEUE", "PROG", "READY", "FAIL":
+		filter.Status = status
+	default:
+		return filter, errors.New(messages.InvalidDeploymentStatusMessage)
+	}
 
-	listInput := &s3.ListObjectsV2Input{
-		Bucket: aws.String(bucketName),
-		Prefix: aws.String(prefix),
+	var err error
+	if filter.From, err = parseFilterTime(ctx.Query("from")); err != nil {
+		return filter, errors.New(messages.InvalidTimeRangeMessage)
 	}
-	listOutput, err := s3Client.ListObjectsV2(context.TODO(), listInput)
-	if err != nil {
-		return fmt.Errorf("failed to list S3 objects for deployment %d: %w", deploymentID, err)
+	if filter.To, err = parseFilterTime(ctx.Query("to")); err != nil {
+		return filter, errors.New(messages.InvalidTimeRangeMessage)
 	}
-
-	// Collect object keys to delete
-	var objectsToDelete []s3Types.ObjectIdentifier
-	for _, obj := range listOutput.Contents {
-		objectsToDelete = append(objectsToDelete, s3Types.ObjectIdentifier{
-			Key: obj.Key,
-		})
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, errors.New(messages.InvalidTimeRangeMessage)
 	}
 
-	// Delete the objects
-	if len(objectsToDelete) > 0 {
-		deleteInput := &s3.DeleteObjectsInput{
-			Bucket: aws.String(bucketName),
-			Delete: &s3Types.Delete{
-				Objects: objectsToDelete,
-				Quiet:   aws.Bool(true),
-			},
-		}
-		_, err := s3Client.DeleteObjects(context.TODO(), deleteInput)
-		if err != nil {
-			return fmt.Errorf("failed to delete S3 objects for deployment %d: %w", deploymentID, err)
-		}
+	if filter.Branch = ctx.Query("branch"); filter.Branch != "" && !isValidBranch(filter.Branch) {
+		return filter, errors.New(messages.InvalidBranchMessage)
 	}
-
-	return nil
-}
-
-func getItemPerPageValue(ctx *gin.Context) int {
-	val, err := strconv.Atoi(ctx.DefaultQuery("per_page", strconv.Itoa(constants.DefaultPerPageSize)))
-	if err != nil {
-		logger.WithRequest(ctx).Errorln("error while extracting item per-page value: ", err)
-		return constants.DefaultPerPageSize
+	filter.CommitSha = strings.ToLower(ctx.Query("commit_sha"))
+	if filter.CommitSha != "" && !commitShaPrefixPattern.MatchString(filter.CommitSha) {
+		return filter, errors.New(messages.InvalidCommitShaMessage)
+	}
+	switch trigger := strings.ToLower(ctx.Query("trigger")); trigger {
+	case "", model.TriggerManual, model.TriggerWebhook, model.TriggerApiToken, model.TriggerRedeploy:
+		filter.TriggerSource = trigger
+	default:
+		return filter, errors.New(messages.InvalidTriggerSourceMessage)
 	}
-	return val
+	return filter, nil
 }
 
-func calculateTotalPages(page, itemsPerPage int) int {
-	if page <= 0 {
-		return 1
+// parseFilterTime accepts RFC3339 timestamps and dates, an empty value is the zero time
+func parseFilterTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.UTC(), nil
 	}
-	return (page + itemsPerPage - 1) / itemsPerPage
+	return time.Parse(time.DateOnly, value)
 }
@@ -7,7 +7,9 @@ import (
 	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
 	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
 	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/pagination"
 	model "github.com/swarajkumarsingh/turbo-deploy/models/deployment_log"
+	projectModel "github.com/swarajkumarsingh/turbo-deploy/models/project"
 )
 
 // get all user Deployment - from logs table
@@ -15,26 +17,36 @@ func GetDeploymentLogs(ctx *gin.Context) {
 	defer errorHandler.Recovery(ctx, http.StatusConflict)
 	reqCtx := ctx.Request.Context()
 
-	page := getCurrentPageValue(ctx)
-	itemsPerPage := getItemPerPageValue(ctx)
-	offset := getOffsetValue(page, itemsPerPage)
+	params, err := pagination.GetParams(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+
+	filter, err := getDeploymentLogFilter(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
 
 	deploymentId, valid := getDeploymentIdFromReq(ctx)
 	if !valid {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidDeploymentIdMessage)
 	}
 
-	rows, err := model.GetDeploymentLogsPaginatedValue(reqCtx, deploymentId, itemsPerPage, offset)
+	rows, err := model.GetDeploymentLogsAfter(reqCtx, deploymentId, filter, params.After, params.FetchLimit())
 	if err != nil {
-		logger.WithRequest(ctx).Errorln(deploymentId, itemsPerPage, offset, err.Error())
+		logger.WithRequest(ctx).Errorln(deploymentId, params.After, params.Limit, err.Error())
 		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveDeploymentLogsMessage)
 	}
 	defer rows.Close()
 
 	logs := make([]gin.H, 0)
+	fetched, lastId := 0, int64(0)
 
 	for rows.Next() {
-		var id int
+		if fetched++; fetched > params.Limit {
+			break
+		}
+		var id int64
 		var logEntry gin.H
 		var deployment_id, project_id, message, stack, log_type, timestamp string
 
@@ -52,6 +64,7 @@ func GetDeploymentLogs(ctx *gin.Context) {
 			"created_at":    timestamp,
 		}
 		logs = append(logs, logEntry)
+		lastId = id
 	}
 
 	if err := rows.Err(); err != nil {
@@ -59,13 +72,91 @@ func GetDeploymentLogs(ctx *gin.Context) {
 		return
 	}
 
-	totalLogs := model.GetTotalDeploymentLogsCount(reqCtx, deploymentId)
-
 	ctx.JSON(http.StatusOK, gin.H{
 		"logs":        logs,
-		"page":        page,
-		"per_page":    itemsPerPage,
-		"total_logs":  totalLogs,
-		"total_pages": calculateTotalPages(totalLogs, itemsPerPage),
+		"limit":       params.Limit,
+		"next_cursor": params.NextCursor(fetched, lastId),
+	})
+}
+
+// search the logs of a deployment
+func SearchDeploymentLogs(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+
+	deploymentId, valid := getDeploymentIdFromReq(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidDeploymentIdMessage)
+	}
+
+	userId, valid := getUserIdFromReq(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
+	}
+
+	filter, err := getLogSearchFilter(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+	// the search only matches deployments of projects owned by the user
+	filter.UserId = userId
+	filter.DeploymentId = deploymentId
+
+	searchLogs(ctx, filter)
+}
+
+// search the logs of every deployment of a project
+func SearchProjectLogs(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+
+	projectId, valid := getProjectIdFromReq(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidProjectIdMessage)
+	}
+
+	userId, valid := getUserIdFromReq(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
+	}
+
+	project, err := projectModel.GetProjectById(ctx.Request.Context(), projectId)
+	if err != nil || project.UserId != userId {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.ProjectNotFoundMessage)
+	}
+
+	filter, err := getLogSearchFilter(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+	filter.UserId = userId
+	filter.ProjectId = projectId
+
+	searchLogs(ctx, filter)
+}
+
+func searchLogs(ctx *gin.Context, filter model.LogSearchFilter) {
+	params, err := pagination.GetParams(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+
+	results, err := model.SearchDeploymentLogs(ctx.Request.Context(), filter, params.After, params.FetchLimit())
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(filter.DeploymentId, filter.ProjectId, err.Error())
+		logger.WithRequest(ctx).Panicln(messages.FailedToSearchDeploymentLogsMessage)
+	}
+
+	fetched, lastId := len(results), int64(0)
+	if fetched > params.Limit {
+		results = results[:params.Limit]
+	}
+	if len(results) > 0 {
+		lastId = results[len(results)-1].Id
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":       false,
+		"logs":        results,
+		"limit":       params.Limit,
+		"next_cursor": params.NextCursor(fetched, lastId),
 	})
 }
@@ -1,12 +1,16 @@
 package deployment_logs
 
 import (
-	"strconv"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/swarajkumarsingh/turbo-deploy/constants"
+	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
 	"github.com/swarajkumarsingh/turbo-deploy/functions/general"
-	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	model "github.com/swarajkumarsingh/turbo-deploy/models/deployment_log"
 )
 
 func getDeploymentIdFromReq(ctx *gin.Context) (int, bool) {
@@ -24,41 +28,51 @@ func getDeploymentIdFromReq(ctx *gin.Context) (int, bool) {
 	return id, true
 }
 
-func getCurrentPageValue(ctx *gin.Context) int {
-	pageStr := ctx.DefaultQuery("page", strconv.Itoa(constants.DefaultPageSize))
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		logger.WithRequest(ctx).Errorln("Invalid page value; defaulting to 1:", err)
-		return 1
+// getDeploymentLogFilter reads log_type and the message search q from the query
+func getDeploymentLogFilter(ctx *gin.Context) (model.DeploymentLogFilter, error) {
+	filter := model.DeploymentLogFilter{Query: strings.TrimSpace(ctx.Query("q"))}
+
+	switch logType := strings.ToUpper(ctx.Query("log_type")); logType {
+	case "", "INFO", "WARN", "ERROR":
+		filter.LogType = logType
+	default:
+		return filter, errors.New(messages.InvalidLogTypeMessage)
 	}
-	return page
+	return filter, nil
 }
 
-func getItemPerPageValue(ctx *gin.Context) int {
-	perPageStr := ctx.DefaultQuery("per_page", strconv.Itoa(constants.DefaultPerPageSize))
-	perPage, err := strconv.Atoi(perPageStr)
-	if err != nil || perPage <= 0 || perPage > 100 {
-		logger.WithRequest(ctx).Errorln("Invalid per_page value; defaulting to:", constants.DefaultPerPageSize)
-		return constants.DefaultPerPageSize
+// getLogSearchFilter reads the required search query q and an optional log_type
+func getLogSearchFilter(ctx *gin.Context) (model.LogSearchFilter, error) {
+	logFilter, err := getDeploymentLogFilter(ctx)
+	if err != nil {
+		return model.LogSearchFilter{}, err
+	}
+	if logFilter.Query == "" || utf8.RuneCountInString(logFilter.Query) > constants.MaxSearchQueryLength {
+		return model.LogSearchFilter{}, errors.New(messages.InvalidSearchQueryMessage)
 	}
-	return perPage
+	return model.LogSearchFilter{LogType: logFilter.LogType, Query: logFilter.Query}, nil
 }
 
-func getOffsetValue(page int, itemsPerPage int) int {
-	if page < 1 {
-		page = 1
+func getProjectIdFromReq(ctx *gin.Context) (int, bool) {
+	projectId := ctx.Param("pid")
+	valid := general.SQLInjectionValidation(projectId)
+
+	if !valid {
+		return 0, false
 	}
-	if itemsPerPage < 1 {
-		itemsPerPage = constants.DefaultPerPageSize
+	id, err := general.IsInt(projectId)
+	if err != nil {
+		return 0, false
 	}
-	return (page - 1) * itemsPerPage
-}
 
+	return id, true
+}
 
-func calculateTotalPages(totalLogs, itemsPerPage int) int {
-	if itemsPerPage <= 0 {
-		return 1
+func getUserIdFromReq(ctx *gin.Context) (string, bool) {
+	uid, valid := ctx.Get(constants.UserIdMiddlewareConstant)
+	if !valid || uid == nil || fmt.Sprintf("%v", uid) == "" {
+		return "", false
 	}
-	totalPages := (totalLogs + itemsPerPage - 1) / itemsPerPage
-	return totalPages
+
+	return fmt.Sprintf("%v", uid), true
 }
@@ -0,0 +1,109 @@
+package project
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
+	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/audit"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	model "github.com/swarajkumarsingh/turbo-deploy/models/project"
+)
+
+// get project access controls - password protection and ip allow-list
+func GetProjectAccess(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	project := getOwnedProject(ctx)
+
+	access, err := model.GetProjectAccess(reqCtx, project.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.ProjectAccessNotFoundMessage)
+		}
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":        false,
+		"access":       access,
+		"has_password": access.PasswordHash != "",
+	})
+}
+
+// update project access controls
+func UpdateProjectAccess(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	project := getOwnedProject(ctx)
+
+	body, err := getProjectAccessBody(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+
+	cidrs, err := normalizeCIDRs(body.AllowedCIDRs)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidAllowedCIDRMessage)
+	}
+
+	// keep the stored password when the body does not rotate it
+	passwordHash := ""
+	existing, err := model.GetProjectAccess(reqCtx, project.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+	if err == nil {
+		passwordHash = existing.PasswordHash
+	}
+	if body.Password != "" {
+		passwordHash, err = hashAccessPassword(body.Password)
+		if err != nil {
+			logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+		}
+	}
+
+	if !hasRequiredAccessCredentials(body.AccessMode, body.Username, passwordHash) {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.AccessCredentialsRequiredMessage)
+	}
+
+	if err := model.UpsertProjectAccess(reqCtx, project.Id, body.AccessMode, body.Username, passwordHash, cidrs); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+	if updated, err := model.GetProjectAccess(reqCtx, project.Id); err == nil {
+		var before interface{}
+		if existing.ProjectId != 0 {
+			before = existing
+		}
+		audit.Record(ctx, "project.access.update", "project", project.Id, before, updated)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":   false,
+		"message": "project access updated successfully",
+	})
+}
+
+// remove project access controls, making the site public again
+func DeleteProjectAccess(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	project := getOwnedProject(ctx)
+	existing, _ := model.GetProjectAccess(reqCtx, project.Id)
+
+	if err := model.DeleteProjectAccess(reqCtx, project.Id); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, err)
+	}
+	audit.Record(ctx, "project.access.delete", "project", project.Id, existing, nil)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":   false,
+		"message": "project access removed successfully",
+	})
+}
@@ -0,0 +1,59 @@
+package project
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
+	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
+	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	accessLogModel "github.com/swarajkumarsingh/turbo-deploy/models/access_log"
+)
+
+// get project traffic analytics - page views, top paths and status distribution
+func GetProjectAnalytics(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	project := getOwnedProject(ctx)
+
+	from, to, err := getAnalyticsRange(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidTimeRangeMessage)
+	}
+
+	summary, err := accessLogModel.GetSummary(reqCtx, project.Id, from, to)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveAnalyticsMessage)
+	}
+
+	topPaths, err := accessLogModel.GetTopPaths(reqCtx, project.Id, from, to, constants.DefaultAnalyticsTopPaths)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveAnalyticsMessage)
+	}
+
+	statuses, err := accessLogModel.GetStatusDistribution(reqCtx, project.Id, from, to)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveAnalyticsMessage)
+	}
+
+	daily, err := accessLogModel.GetDailyCounts(reqCtx, project.Id, from, to)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveAnalyticsMessage)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":               false,
+		"from":                from,
+		"to":                  to,
+		"summary":             summary,
+		"top_paths":           topPaths,
+		"status_distribution": statuses,
+		"daily":               daily,
+	})
+}
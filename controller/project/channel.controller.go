@@ -0,0 +1,192 @@
+package project
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
+	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/notifier"
+	model "github.com/swarajkumarsingh/turbo-deploy/models/project"
+)
+
+// add a slack, discord, chat webhook or email channel notified when deployments finish, email channels stay
+// inactive until the code sent to the address is verified
+func CreateNotificationChannel(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	project := getOwnedProject(ctx)
+
+	body, err := getCreateChannelBody(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+
+	var code string
+	var verification model.ChannelVerification
+	active := body.Active == nil || *body.Active
+	if body.Type == notifier.TypeEmail {
+		code, verification, err = newChannelVerification()
+		if err != nil {
+			logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+		}
+		active = false
+	}
+
+	channel, err := model.CreateNotificationChannel(reqCtx, model.NotificationChannel{
+		ProjectId: project.Id,
+		Type:      body.Type,
+		Target:    body.Target,
+		OnSuccess: body.OnSuccess == nil || *body.OnSuccess,
+		OnFailure: body.OnFailure == nil || *body.OnFailure,
+		Active:    active,
+	}, verification)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+
+	message := "notification channel created successfully"
+	if code != "" {
+		if err := sendChannelVerification(channel, code); err != nil {
+			logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+		}
+		message = "notification channel created, enter the code sent to " + channel.Target + " to activate it"
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"error":   false,
+		"message": message,
+		"channel": channel,
+	})
+}
+
+func GetNotificationChannels(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	project := getOwnedProject(ctx)
+
+	channels, err := model.GetNotificationChannels(reqCtx, project.Id)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveChannelsMessage)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":    false,
+		"channels": channels,
+	})
+}
+
+// update the target, the events or pause a channel, the type cannot change
+func UpdateNotificationChannel(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	channel := getOwnedChannel(ctx)
+
+	body, err := getUpdateChannelBody(ctx, channel.Type)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+
+	// a new address has to be verified again before the channel sends to it
+	reverify := channel.Type == notifier.TypeEmail && body.Target != "" && body.Target != channel.Target
+	if reverify {
+		channel.VerifiedAt = nil
+		channel.Active = false
+	}
+
+	if body.Target != "" {
+		channel.Target = body.Target
+	}
+	if body.OnSuccess != nil {
+		channel.OnSuccess = *body.OnSuccess
+	}
+	if body.OnFailure != nil {
+		channel.OnFailure = *body.OnFailure
+	}
+	if body.Active != nil {
+		if *body.Active && channel.VerifiedAt == nil {
+			logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.ChannelNotVerifiedMessage)
+		}
+		channel.Active = *body.Active
+	}
+
+	if err := model.UpdateNotificationChannel(reqCtx, channel); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+
+	message := "notification channel updated successfully"
+	if reverify {
+		resetChannelVerification(ctx, channel)
+		message = "notification channel updated, enter the code sent to " + channel.Target + " to activate it"
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":   false,
+		"message": message,
+		"channel": channel,
+	})
+}
+
+// activate an email channel with the code sent to its address
+func VerifyNotificationChannel(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	channel := getOwnedChannel(ctx)
+
+	body, err := getVerifyChannelBody(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+
+	verified, err := model.VerifyNotificationChannel(reqCtx, channel.Id, hashVerificationCode(body.Code))
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+	if !verified {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidVerificationCodeMessage)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":   false,
+		"message": "notification channel verified successfully",
+	})
+}
+
+// send a new verification code to an email channel, the previous code stops working
+func ResendChannelVerification(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+
+	channel := getOwnedChannel(ctx)
+	if channel.Type != notifier.TypeEmail || channel.VerifiedAt != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.ChannelAlreadyVerifiedMessage)
+	}
+
+	resetChannelVerification(ctx, channel)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":   false,
+		"message": "verification code sent to " + channel.Target,
+	})
+}
+
+func DeleteNotificationChannel(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	channel := getOwnedChannel(ctx)
+
+	if err := model.DeleteNotificationChannel(reqCtx, channel.ProjectId, channel.Id); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, err)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":   false,
+		"message": "notification channel deleted successfully",
+	})
+}
@@ -0,0 +1,118 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
+	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
+	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/framework"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	validators "github.com/swarajkumarsingh/turbo-deploy/functions/validator"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/source"
+	model "github.com/swarajkumarsingh/turbo-deploy/models/project"
+)
+
+// detect the framework of a repository and propose build settings
+func DetectFramework(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+
+	var body model.DetectProjectBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidBodyMessage)
+	}
+	if err := validators.ValidateStruct(body); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+	if !isValidBuildDir(body.RootDir) {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidBuildDirMessage)
+	}
+
+	result, detected, err := detectBuildSettings(ctx.Request.Context(), body.SourceCode, body.SourceCodeUrl, body.RootDir)
+	if errors.Is(err, source.ErrUnsupportedSource) || errors.Is(err, source.ErrInvalidRepository) {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidSourceURLMessage)
+	}
+	if errors.Is(err, source.ErrNotFound) {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.GithubRepoNotFoundOrPrivate)
+	}
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(body.SourceCodeUrl, err.Error())
+		logger.WithRequest(ctx).Panicln(http.StatusBadGateway, messages.FailedToDetectFrameworkMessage)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":    false,
+		"detected": detected,
+		"result":   result,
+	})
+}
+
+// detectBuildSettings reads the root dir of the repository through its source provider and runs the
+// framework detection on it, suggestions that would not pass validateBuildSettings are dropped
+func detectBuildSettings(ctx context.Context, sourceCode, repositoryUrl, rootDir string) (framework.Result, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, constants.FrameworkDetectionTimeout)
+	defer cancel()
+
+	provider, err := source.New(sourceCode, repositoryUrl, constants.GithubApiToken)
+	if err != nil {
+		return framework.Result{}, false, err
+	}
+
+	names, err := provider.ListDir(ctx, rootDir)
+	if err != nil {
+		return framework.Result{}, false, err
+	}
+
+	dir := framework.Dir{Names: names, Files: make(map[string][]byte)}
+	for _, name := range names {
+		if !framework.Wanted(name) {
+			continue
+		}
+		content, err := provider.ReadFile(ctx, path.Join(rootDir, name))
+		if err != nil && !errors.Is(err, source.ErrFileTooLarge) {
+			return framework.Result{}, false, err
+		}
+		dir.Files[name] = content
+	}
+
+	result, detected := framework.Detect(dir)
+	if !isValidBuildDir(result.OutputDir) {
+		result.Warnings = append(result.Warnings, "output directory "+result.OutputDir+" is outside the repository")
+		result.OutputDir = ""
+	}
+	if !isValidBuildCommand(result.InstallCommand) || !isValidBuildCommand(result.BuildCommand) {
+		result.InstallCommand, result.BuildCommand = "", ""
+	}
+	return result, detected, nil
+}
+
+// applyDetectedBuildSettings fills the build settings missing from the body with the detected ones,
+// detection is best effort and never blocks creating the project
+func applyDetectedBuildSettings(ctx *gin.Context, body *model.ProjectBody) {
+	if body.InstallCommand != "" && body.BuildCommand != "" && body.OutputDir != "" {
+		return
+	}
+
+	result, detected, err := detectBuildSettings(ctx.Request.Context(), body.SourceCode, body.SourceCodeUrl, body.RootDir)
+	if err != nil {
+		logger.WithRequest(ctx).Warnln("framework detection failed for", body.SourceCodeUrl, err)
+		return
+	}
+	if !detected {
+		return
+	}
+
+	if body.InstallCommand == "" {
+		body.InstallCommand = result.InstallCommand
+	}
+	if body.BuildCommand == "" {
+		body.BuildCommand = result.BuildCommand
+	}
+	if body.OutputDir == "" {
+		body.OutputDir = result.OutputDir
+	}
+}
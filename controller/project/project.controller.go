@@ -1,12 +1,17 @@
 package project
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
 	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
 	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/audit"
 	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/pagination"
 	model "github.com/swarajkumarsingh/turbo-deploy/models/project"
 )
 
@@ -42,14 +47,18 @@ func CreateProject(ctx *gin.Context) {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.GithubRepoNotFoundOrPrivate)
 	}
 
+	// Default the missing build settings to the detected ones
+	applyDetectedBuildSettings(ctx, &body)
+
 	// Add to project table
-	subDomainAlreadyExists, err := model.CreateProject(reqCtx, body)
+	projectId, subDomainAlreadyExists, err := model.CreateProject(reqCtx, body)
 	if subDomainAlreadyExists {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
 	}
 	if err != nil {
 		logger.WithRequest(ctx).Panicln(err)
 	}
+	audit.Record(ctx, "project.create", "project", projectId, nil, body)
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"error":   false,
@@ -83,73 +92,82 @@ func GetAllProject(ctx *gin.Context) {
 	defer errorHandler.Recovery(ctx, http.StatusConflict)
 	reqCtx := ctx.Request.Context()
 
-	page := getCurrentPageValue(ctx)
-	itemsPerPage := getItemPerPageValue(ctx)
-	offset := getOffsetValue(page, itemsPerPage)
+	params, err := pagination.GetParams(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
 
 	userId, valid := getUserIdFromReq(ctx)
 	if !valid {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
 	}
 
-	rows, err := model.GetProjectListPaginatedValue(reqCtx, userId, itemsPerPage, offset)
+	rows, err := model.GetProjectListAfter(reqCtx, userId, params.After, params.FetchLimit())
 	if err != nil {
 		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveProjectsMessage)
 	}
 	defer rows.Close()
 
 	projects := make([]gin.H, 0)
+	fetched, lastId := 0, int64(0)
 
 	for rows.Next() {
-		var id int
+		if fetched++; fetched > params.Limit {
+			break
+		}
+		var id int64
 		var name, subdomain, language string
 		if err := rows.Scan(&id, &name, &subdomain, &language); err != nil {
 			logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveProjectsMessage)
 		}
 		projects = append(projects, gin.H{"id": id, "name": name, "subdomain": subdomain, "language": language})
+		lastId = id
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"users":       projects,
-		"page":        page,
-		"per_page":    itemsPerPage,
-		"total_pages": calculateTotalPages(page, itemsPerPage),
+		"limit":       params.Limit,
+		"next_cursor": params.NextCursor(fetched, lastId),
 	})
 }
 
-// update project - projectName, customDomain
+// update project - projectName, subdomain, maintenance mode and page, build output retention
 func UpdateProject(ctx *gin.Context) {
 	defer errorHandler.Recovery(ctx, http.StatusConflict)
 	reqCtx := ctx.Request.Context()
 
-	pid, valid := getProjectIdFromParam(ctx)
-	if !valid {
-		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidProjectIdMessage)
-	}
+	// only the owner may change the project, the maintenance page is served as is on its host
+	project := getOwnedProject(ctx)
+	pid := project.Id
 
-	// get projectName and customDomain
+	// get projectName, subdomain and maintenance settings
 	body, err := getUpdateProjectBody(ctx)
 	if err != nil {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
 	}
 
 	// Check sub-domain availability
-	available, err := model.IsSubDomainAvailable(reqCtx, body.Subdomain)
-	if err != nil {
-		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
-	}
-	if !available {
-		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.SubDomainAlreadyExists)
+	if body.Subdomain != "" && body.Subdomain != project.Subdomain {
+		available, err := model.IsSubDomainAvailable(reqCtx, body.Subdomain)
+		if err != nil {
+			logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+		}
+		if !available {
+			logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.SubDomainAlreadyExists)
+		}
 	}
 
 	// update in project DB
-	subDomainAlreadyExists, err := model.UpdateProject(reqCtx, pid, body.Name, body.Subdomain)
+	subDomainAlreadyExists, err := model.UpdateProject(reqCtx, pid, body)
 	if subDomainAlreadyExists {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
 	}
 	if err != nil {
 		logger.WithRequest(ctx).Panicln(err)
 	}
+	if updated, err := model.GetProjectById(reqCtx, pid); err == nil {
+		audit.Record(ctx, "project.update", "project", pid, project, updated)
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"error":   false,
@@ -172,6 +190,7 @@ func DeleteAllProject(ctx *gin.Context) {
 	if err := model.DeleteAllProjectFromUser(reqCtx, uid); err != nil {
 		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
 	}
+	audit.Record(ctx, "project.delete_all", "user", uid, nil, nil)
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"error":   false,
@@ -190,13 +209,60 @@ func DeleteProject(ctx *gin.Context) {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
 	}
 
+	project, err := model.GetProjectById(reqCtx, pid)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.ProjectNotFoundMessage)
+	}
+
 	// delete project
 	if err := model.DeleteProjectFromUser(reqCtx, pid); err != nil {
 		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
 	}
+	audit.Record(ctx, "project.delete", "project", pid, project, nil)
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"error":   false,
 		"message": "project deleted successfully",
 	})
 }
+
+// list the projects in the user's trash
+func GetTrashedProjects(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+
+	userId, valid := getUserIdFromReq(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
+	}
+
+	projects, err := model.GetTrashedProjects(ctx.Request.Context(), userId, constants.TrashRetention)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveProjectsMessage)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":    false,
+		"projects": projects,
+	})
+}
+
+// restore a deleted project within the retention window
+func RestoreProject(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	project := getOwnedTrashedProject(ctx)
+
+	if err := model.RestoreProject(ctx.Request.Context(), project.Id, constants.TrashRetention); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.ProjectNotInTrashMessage)
+		}
+		logger.WithRequest(ctx).Panicln(err)
+	}
+	if restored, err := model.GetProjectById(ctx.Request.Context(), project.Id); err == nil {
+		audit.Record(ctx, "project.restore", "project", project.Id, project, restored)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":   false,
+		"message": "project restored successfully",
+	})
+}
@@ -1,11 +1,19 @@
 package project
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,7 +22,11 @@ import (
 	"github.com/swarajkumarsingh/turbo-deploy/functions/general"
 	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
 	validators "github.com/swarajkumarsingh/turbo-deploy/functions/validator"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/notifier"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/webhook"
 	model "github.com/swarajkumarsingh/turbo-deploy/models/project"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func getUserIdFromReq(ctx *gin.Context) (string, bool) {
@@ -27,12 +39,12 @@ func getUserIdFromReq(ctx *gin.Context) (string, bool) {
 }
 
 func getCurrentPageValue(ctx *gin.Context) int {
-	val, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	if err != nil {
-		logger.WithRequest(ctx).Errorln("error while extracting current page value: ", err)
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		logger.WithRequest(ctx).Errorln("Invalid page value; defaulting to 1:", err)
 		return 1
 	}
-	return val
+	return page
 }
 
 func getOffsetValue(page int, itemsPerPage int) int {
@@ -40,19 +52,19 @@ func getOffsetValue(page int, itemsPerPage int) int {
 }
 
 func getItemPerPageValue(ctx *gin.Context) int {
-	val, err := strconv.Atoi(ctx.DefaultQuery("per_page", strconv.Itoa(constants.DefaultPerPageSize)))
-	if err != nil {
-		logger.WithRequest(ctx).Errorln("error while extracting item per-page value: ", err)
+	perPage, err := strconv.Atoi(ctx.DefaultQuery("per_page", strconv.Itoa(constants.DefaultPerPageSize)))
+	if err != nil || perPage <= 0 || perPage > 100 {
+		logger.WithRequest(ctx).Errorln("Invalid per_page value; defaulting to:", constants.DefaultPerPageSize)
 		return constants.DefaultPerPageSize
 	}
-	return val
+	return perPage
 }
 
-func calculateTotalPages(page, itemsPerPage int) int {
-	if page <= 0 {
+func calculateTotalPages(total, itemsPerPage int) int {
+	if itemsPerPage <= 0 {
 		return 1
 	}
-	return (page + itemsPerPage - 1) / itemsPerPage
+	return (total + itemsPerPage - 1) / itemsPerPage
 }
 
 func IsValidGitHubURL(url string) bool {
@@ -125,6 +137,10 @@ func getCreateProjectBody(ctx *gin.Context) (model.ProjectBody, error) {
 		return body, errors.New(messages.InvalidBodyMessage)
 	}
 
+	if err := validateBuildSettings(body.BuildSettings); err != nil {
+		return body, err
+	}
+
 	return body, nil
 }
 
@@ -138,8 +154,475 @@ func getUpdateProjectBody(ctx *gin.Context) (model.UpdateProjectBody, error) {
 		return body, err
 	}
 
-	if !general.IsAlphanumeric(body.Name) || !general.IsAlphanumeric(body.Subdomain) {
+	if body.Name == "" && body.Subdomain == "" && body.MaintenanceMode == nil && body.MaintenancePage == nil &&
+		body.RetentionKeepReady == nil && body.RetentionFailDays == nil && body.InstallCommand == nil && body.BuildCommand == nil &&
+		body.OutputDir == nil && body.RootDir == nil && body.RuntimeVersion == nil {
+		return body, errors.New(messages.InvalidBodyMessage)
+	}
+
+	if (body.Name != "" && !general.IsAlphanumeric(body.Name)) || (body.Subdomain != "" && !general.IsAlphanumeric(body.Subdomain)) {
+		return body, errors.New(messages.InvalidBodyMessage)
+	}
+
+	if body.MaintenancePage != nil && len(*body.MaintenancePage) > constants.MaxMaintenancePageSize {
+		return body, errors.New(messages.MaintenancePageTooLargeMessage)
+	}
+
+	// absent settings are left unchanged, so only the present ones need to be valid
+	settings := model.BuildSettings{
+		InstallCommand: valueOrEmpty(body.InstallCommand),
+		BuildCommand:   valueOrEmpty(body.BuildCommand),
+		OutputDir:      valueOrEmpty(body.OutputDir),
+		RootDir:        valueOrEmpty(body.RootDir),
+		RuntimeVersion: valueOrEmpty(body.RuntimeVersion),
+	}
+	if err := validateBuildSettings(settings); err != nil {
+		return body, err
+	}
+	return body, nil
+}
+
+// validateBuildSettings checks the settings handed to the builder, empty values are the builder defaults.
+// The builder applies the same command rules again before running them
+func validateBuildSettings(settings model.BuildSettings) error {
+	for _, command := range []string{settings.InstallCommand, settings.BuildCommand} {
+		if !isValidBuildCommand(command) {
+			return errors.New(messages.InvalidBuildCommandMessage)
+		}
+	}
+
+	for _, dir := range []string{settings.OutputDir, settings.RootDir} {
+		if !isValidBuildDir(dir) {
+			return errors.New(messages.InvalidBuildDirMessage)
+		}
+	}
+
+	if settings.RuntimeVersion != "" && !general.InArrStr(settings.RuntimeVersion, constants.SupportedRuntimeVersions) {
+		return errors.New(messages.InvalidRuntimeVersionMessage)
+	}
+	return nil
+}
+
+// a build command step runs a package manager with plain arguments, so no quoting, expansion, pipes or redirects
+var (
+	buildCommandStepPattern = regexp.MustCompile(`^(npm|npx|yarn|pnpm)( +[A-Za-z0-9@._:=/+-]+)*$`)
+	buildDirPattern         = regexp.MustCompile(`^[A-Za-z0-9._-]+(/[A-Za-z0-9._-]+)*$`)
+)
+
+// isValidBuildCommand accepts package manager steps joined by &&, like npm ci && npm run build
+func isValidBuildCommand(command string) bool {
+	if command == "" {
+		return true
+	}
+	if len(command) > constants.MaxBuildCommandLength {
+		return false
+	}
+	for _, step := range strings.Split(command, "&&") {
+		if !buildCommandStepPattern.MatchString(strings.TrimSpace(step)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidBuildDir accepts clean relative paths that stay inside the repository, like apps/web or dist
+func isValidBuildDir(dir string) bool {
+	if dir == "" {
+		return true
+	}
+	if len(dir) > constants.MaxBuildDirLength || !buildDirPattern.MatchString(dir) {
+		return false
+	}
+	for _, segment := range strings.Split(dir, "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// getOwnedProject loads the project from the :pid param and panics unless it belongs to the authorized user
+func getOwnedProject(ctx *gin.Context) model.Project {
+	pid, valid := getProjectIdFromParam(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidProjectIdMessage)
+	}
+
+	userId, valid := getUserIdFromReq(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
+	}
+
+	project, err := model.GetProjectById(ctx.Request.Context(), pid)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.ProjectNotFoundMessage)
+	}
+	if project.UserId != userId {
+		logger.WithRequest(ctx).Panicln(http.StatusForbidden, messages.ProjectForbiddenMessage)
+	}
+
+	return project
+}
+
+// getOwnedTrashedProject is getOwnedProject for projects in the trash
+func getOwnedTrashedProject(ctx *gin.Context) model.Project {
+	pid, valid := getProjectIdFromParam(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidProjectIdMessage)
+	}
+
+	userId, valid := getUserIdFromReq(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
+	}
+
+	project, err := model.GetTrashedProjectById(ctx.Request.Context(), pid, constants.TrashRetention)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.ProjectNotInTrashMessage)
+	}
+	if project.UserId != userId {
+		logger.WithRequest(ctx).Panicln(http.StatusForbidden, messages.ProjectForbiddenMessage)
+	}
+
+	return project
+}
+
+func getProjectAccessBody(ctx *gin.Context) (model.ProjectAccessBody, error) {
+	var body model.ProjectAccessBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
 		return body, errors.New(messages.InvalidBodyMessage)
 	}
+
+	if err := validators.ValidateStruct(body); err != nil {
+		return body, err
+	}
+
+	body.Username = strings.TrimSpace(body.Username)
 	return body, nil
 }
+
+// normalizeCIDRs validates the allow-list and turns bare ip addresses into single host networks
+func normalizeCIDRs(values []string) ([]string, error) {
+	cidrs := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip address: %s", value)
+			}
+			if ip.To4() != nil {
+				value = value + "/32"
+			} else {
+				value = value + "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, network.String())
+	}
+	return cidrs, nil
+}
+
+func hasRequiredAccessCredentials(mode, username, passwordHash string) bool {
+	switch mode {
+	case "basic":
+		return username != "" && passwordHash != ""
+	case "password":
+		return passwordHash != ""
+	default:
+		return true
+	}
+}
+
+func hashAccessPassword(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), constants.BcryptHashingCost)
+	return string(bytes), err
+}
+
+// getAnalyticsRange reads the from/to query params as RFC3339 timestamps or dates, defaulting to the last week
+func getAnalyticsRange(ctx *gin.Context) (time.Time, time.Time, error) {
+	to := time.Now().UTC()
+	from := to.Add(-constants.DefaultAnalyticsRange)
+
+	if value := ctx.Query("to"); value != "" {
+		parsed, err := parseAnalyticsTime(value)
+		if err != nil {
+			return from, to, err
+		}
+		to = parsed
+		from = to.Add(-constants.DefaultAnalyticsRange)
+	}
+	if value := ctx.Query("from"); value != "" {
+		parsed, err := parseAnalyticsTime(value)
+		if err != nil {
+			return from, to, err
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) || to.Sub(from) > constants.MaxAnalyticsRange {
+		return from, to, errors.New(messages.InvalidTimeRangeMessage)
+	}
+	return from, to, nil
+}
+
+func parseAnalyticsTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.UTC(), nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+func getWebhookIdFromParam(ctx *gin.Context) (int, bool) {
+	wid, err := strconv.Atoi(ctx.Param("wid"))
+	if err != nil || wid <= 0 {
+		return 0, false
+	}
+	return wid, true
+}
+
+func getWebhookDeliveryIdFromParam(ctx *gin.Context) (int64, bool) {
+	did, err := strconv.ParseInt(ctx.Param("did"), 10, 64)
+	if err != nil || did <= 0 {
+		return 0, false
+	}
+	return did, true
+}
+
+// getOwnedWebhook loads the webhook from the :wid param, scoped to the project owned by the authorized user
+func getOwnedWebhook(ctx *gin.Context) model.Webhook {
+	project := getOwnedProject(ctx)
+
+	wid, valid := getWebhookIdFromParam(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidWebhookIdMessage)
+	}
+
+	hook, err := model.GetWebhookById(ctx.Request.Context(), project.Id, wid)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.WebhookNotFoundMessage)
+	}
+	return hook
+}
+
+func getCreateWebhookBody(ctx *gin.Context) (model.WebhookBody, error) {
+	var body model.WebhookBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		return body, errors.New(messages.InvalidBodyMessage)
+	}
+
+	if err := validators.ValidateStruct(body); err != nil {
+		return body, err
+	}
+
+	if err := validateWebhook(ctx.Request.Context(), body.URL, body.Events); err != nil {
+		return body, err
+	}
+	return body, nil
+}
+
+func getUpdateWebhookBody(ctx *gin.Context) (model.UpdateWebhookBody, error) {
+	var body model.UpdateWebhookBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		return body, errors.New(messages.InvalidBodyMessage)
+	}
+
+	if err := validators.ValidateStruct(body); err != nil {
+		return body, err
+	}
+
+	if body.URL == "" && body.Events == nil && body.Active == nil && !body.RotateSecret {
+		return body, errors.New(messages.InvalidBodyMessage)
+	}
+
+	if err := validateWebhook(ctx.Request.Context(), body.URL, body.Events); err != nil {
+		return body, err
+	}
+	return body, nil
+}
+
+// validateWebhook checks the url scheme, that the url does not point at an internal address and the subscribed
+// events, empty values are not checked
+func validateWebhook(ctx context.Context, rawURL string, events []string) error {
+	if rawURL != "" && !isHTTPURL(rawURL) {
+		return errors.New(messages.InvalidWebhookURLMessage)
+	}
+	if rawURL != "" && webhook.ValidateURL(ctx, rawURL) != nil {
+		return errors.New(messages.WebhookURLNotPublicMessage)
+	}
+
+	for _, event := range events {
+		if !webhook.ValidEvent(event) {
+			return errors.New(messages.InvalidWebhookEventMessage)
+		}
+	}
+	return nil
+}
+
+// generateWebhookSecret returns the key deliveries are signed with
+func generateWebhookSecret() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(bytes), nil
+}
+
+func isHTTPURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+func getChannelIdFromParam(ctx *gin.Context) (int, bool) {
+	cid, err := strconv.Atoi(ctx.Param("cid"))
+	if err != nil || cid <= 0 {
+		return 0, false
+	}
+	return cid, true
+}
+
+// getOwnedChannel loads the notification channel from the :cid param, scoped to the project owned by the authorized user
+func getOwnedChannel(ctx *gin.Context) model.NotificationChannel {
+	project := getOwnedProject(ctx)
+
+	cid, valid := getChannelIdFromParam(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidChannelIdMessage)
+	}
+
+	channel, err := model.GetNotificationChannelById(ctx.Request.Context(), project.Id, cid)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.ChannelNotFoundMessage)
+	}
+	return channel
+}
+
+func getCreateChannelBody(ctx *gin.Context) (model.NotificationChannelBody, error) {
+	var body model.NotificationChannelBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		return body, errors.New(messages.InvalidBodyMessage)
+	}
+
+	if err := validators.ValidateStruct(body); err != nil {
+		return body, err
+	}
+
+	if err := validateChannelTarget(ctx.Request.Context(), body.Type, body.Target); err != nil {
+		return body, err
+	}
+	return body, nil
+}
+
+func getUpdateChannelBody(ctx *gin.Context, channelType string) (model.UpdateNotificationChannelBody, error) {
+	var body model.UpdateNotificationChannelBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		return body, errors.New(messages.InvalidBodyMessage)
+	}
+
+	if err := validators.ValidateStruct(body); err != nil {
+		return body, err
+	}
+
+	if body.Target == "" && body.OnSuccess == nil && body.OnFailure == nil && body.Active == nil {
+		return body, errors.New(messages.InvalidBodyMessage)
+	}
+
+	if body.Target != "" {
+		if err := validateChannelTarget(ctx.Request.Context(), channelType, body.Target); err != nil {
+			return body, err
+		}
+	}
+	return body, nil
+}
+
+func getVerifyChannelBody(ctx *gin.Context) (model.VerifyNotificationChannelBody, error) {
+	var body model.VerifyNotificationChannelBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		return body, errors.New(messages.InvalidBodyMessage)
+	}
+
+	if err := validators.ValidateStruct(body); err != nil {
+		return body, err
+	}
+	return body, nil
+}
+
+// newChannelVerification returns a code for an email channel, only its hash is stored
+func newChannelVerification() (string, model.ChannelVerification, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", model.ChannelVerification{}, err
+	}
+	code := hex.EncodeToString(bytes)
+	return code, model.ChannelVerification{CodeHash: hashVerificationCode(code), TTL: constants.ChannelVerificationTTL}, nil
+}
+
+func hashVerificationCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
+
+// resetChannelVerification pauses an email channel and sends a new code to its address
+func resetChannelVerification(ctx *gin.Context, channel model.NotificationChannel) {
+	code, verification, err := newChannelVerification()
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+	if err := model.ResetNotificationChannelVerification(ctx.Request.Context(), channel.Id, verification); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+	if err := sendChannelVerification(channel, code); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+}
+
+// sendChannelVerification queues the code for the email consumer, which looks the address up by channel id
+func sendChannelVerification(channel model.NotificationChannel, code string) error {
+	body, err := json.Marshal(gin.H{
+		"appName":   constants.TaskDefinitionENVAppName,
+		"event":     "channel_verification",
+		"projectId": strconv.Itoa(channel.ProjectId),
+		"channelId": strconv.Itoa(channel.Id),
+		"code":      code,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	if err := sqs.SendMessage(string(body), constants.TaskDefinitionEmailQueueUrl); err != nil {
+		return fmt.Errorf("failed to send verification code for channel %d: %w", channel.Id, err)
+	}
+	return nil
+}
+
+// validateChannelTarget checks the target is an email address for email channels and otherwise a webhook url
+// that does not point at an internal address
+func validateChannelTarget(ctx context.Context, channelType, target string) error {
+	if channelType == notifier.TypeEmail {
+		if !general.ValidateEmail(target) {
+			return errors.New(messages.InvalidChannelTargetMessage)
+		}
+		return nil
+	}
+
+	if !isHTTPURL(target) {
+		return errors.New(messages.InvalidChannelTargetMessage)
+	}
+	if webhook.ValidateURL(ctx, target) != nil {
+		return errors.New(messages.WebhookURLNotPublicMessage)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package project
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
+	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	model "github.com/swarajkumarsingh/turbo-deploy/models/project"
+)
+
+// subscribe a url to deployment lifecycle events, the signing secret is only returned here
+func CreateWebhook(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	project := getOwnedProject(ctx)
+
+	body, err := getCreateWebhookBody(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+
+	active := body.Active == nil || *body.Active
+	hook, err := model.CreateWebhook(reqCtx, project.Id, body.URL, secret, body.Events, active)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"error":   false,
+		"message": "webhook created successfully",
+		"webhook": hook,
+		"secret":  secret,
+	})
+}
+
+func GetWebhooks(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	project := getOwnedProject(ctx)
+
+	hooks, err := model.GetWebhooks(reqCtx, project.Id)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveWebhooksMessage)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":    false,
+		"webhooks": hooks,
+	})
+}
+
+func GetWebhook(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+
+	hook := getOwnedWebhook(ctx)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":   false,
+		"webhook": hook,
+	})
+}
+
+// update the url, events or active flag of a webhook, optionally rotating its secret
+func UpdateWebhook(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	hook := getOwnedWebhook(ctx)
+
+	body, err := getUpdateWebhookBody(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+
+	if body.URL != "" {
+		hook.URL = body.URL
+	}
+	if body.Events != nil {
+		hook.Events = body.Events
+	}
+	if body.Active != nil {
+		hook.Active = *body.Active
+	}
+
+	response := gin.H{"error": false, "message": "webhook updated successfully"}
+	if body.RotateSecret {
+		hook.Secret, err = generateWebhookSecret()
+		if err != nil {
+			logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+		}
+		response["secret"] = hook.Secret
+	}
+
+	if err := model.UpdateWebhook(reqCtx, hook); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+
+	response["webhook"] = hook
+	ctx.JSON(http.StatusOK, response)
+}
+
+func DeleteWebhook(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	hook := getOwnedWebhook(ctx)
+
+	if err := model.DeleteWebhook(reqCtx, hook.ProjectId, hook.Id); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, err)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":   false,
+		"message": "webhook deleted successfully",
+	})
+}
+
+// list the deliveries of a webhook with their response codes, newest first
+func GetWebhookDeliveries(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	hook := getOwnedWebhook(ctx)
+
+	page := getCurrentPageValue(ctx)
+	itemsPerPage := getItemPerPageValue(ctx)
+	offset := getOffsetValue(page, itemsPerPage)
+
+	deliveries, err := model.GetWebhookDeliveries(reqCtx, hook.Id, itemsPerPage, offset)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveDeliveriesMessage)
+	}
+
+	total, err := model.GetTotalWebhookDeliveriesCount(reqCtx, hook.Id)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveDeliveriesMessage)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":       false,
+		"deliveries":  deliveries,
+		"page":        page,
+		"per_page":    itemsPerPage,
+		"total":       total,
+		"total_pages": calculateTotalPages(total, itemsPerPage),
+	})
+}
+
+// resend the payload of a past delivery with a fresh signature, the result is recorded as a new delivery
+func RedeliverWebhook(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	hook := getOwnedWebhook(ctx)
+
+	did, valid := getWebhookDeliveryIdFromParam(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidWebhookDeliveryIdMessage)
+	}
+
+	if _, err := model.GetWebhookDeliveryById(reqCtx, hook.Id, did); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.WebhookDeliveryNotFoundMessage)
+	}
+
+	delivery, err := model.RedeliverWebhook(reqCtx, did)
+	if err != nil && delivery.Id == 0 {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+
+	// a failed redelivery is still recorded, the response carries its outcome
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":    false,
+		"delivery": delivery,
+	})
+}
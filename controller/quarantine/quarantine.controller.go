@@ -0,0 +1,126 @@
+package quarantine
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
+	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs"
+	model "github.com/swarajkumarsingh/turbo-deploy/models/quarantine"
+)
+
+// list quarantined queue messages, optionally filtered by status and source queue
+func GetQuarantinedMessages(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	page := getCurrentPageValue(ctx)
+	itemsPerPage := getItemPerPageValue(ctx)
+	offset := getOffsetValue(page, itemsPerPage)
+
+	status, valid := getStatusFromQuery(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidQuarantineStatusMessage)
+	}
+	sourceQueue := ctx.Query("source_queue")
+
+	quarantined, err := model.GetQuarantinedMessages(reqCtx, status, sourceQueue, itemsPerPage, offset)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveQuarantineMessage)
+	}
+
+	total, err := model.GetTotalQuarantinedMessagesCount(reqCtx, status, sourceQueue)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveQuarantineMessage)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":       false,
+		"messages":    quarantined,
+		"page":        page,
+		"per_page":    itemsPerPage,
+		"total":       total,
+		"total_pages": calculateTotalPages(total, itemsPerPage),
+	})
+}
+
+func GetQuarantinedMessage(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	id, valid := getQuarantineIdFromParam(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidQuarantineIdMessage)
+	}
+
+	quarantined, err := model.GetQuarantinedMessageById(reqCtx, id)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.QuarantinedMessageNotFoundMessage)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":   false,
+		"message": quarantined,
+	})
+}
+
+// send a quarantined message back to the queue it came from
+func RedriveQuarantinedMessage(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	id, valid := getQuarantineIdFromParam(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidQuarantineIdMessage)
+	}
+
+	quarantined, err := model.GetQuarantinedMessageById(reqCtx, id)
+	if err == sql.ErrNoRows {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.QuarantinedMessageNotFoundMessage)
+	}
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRetrieveQuarantineMessage)
+	}
+
+	claimed, err := model.MarkRedriven(reqCtx, id)
+	if err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		logger.WithRequest(ctx).Panicln(messages.FailedToRedriveMessage)
+	}
+	if !claimed {
+		logger.WithRequest(ctx).Panicln(http.StatusConflict, messages.QuarantinedMessageRedrivenMessage)
+	}
+
+	if err := redrive(quarantined); err != nil {
+		logger.WithRequest(ctx).Errorln(err)
+		if err := model.UnmarkRedriven(reqCtx, id); err != nil {
+			logger.WithRequest(ctx).Errorln(err)
+		}
+		logger.WithRequest(ctx).Panicln(messages.FailedToRedriveMessage)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":   false,
+		"message": fmt.Sprintf("message %d redriven to %s", id, quarantined.SourceQueue),
+	})
+}
+
+func redrive(quarantined model.QuarantinedMessage) error {
+	if !strings.HasSuffix(quarantined.SourceQueue, ".fifo") {
+		return sqs.SendMessage(quarantined.Body, quarantined.SourceQueue)
+	}
+
+	groupId := quarantined.MessageGroupId
+	if groupId == "" {
+		groupId = "redrive"
+	}
+	return sqs.SendFIFOMessage(fmt.Sprintf("redrive-%d", quarantined.Id), quarantined.Body, groupId, quarantined.SourceQueue)
+}
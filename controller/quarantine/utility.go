@@ -0,0 +1,57 @@
+package quarantine
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	model "github.com/swarajkumarsingh/turbo-deploy/models/quarantine"
+)
+
+func getQuarantineIdFromParam(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+func getStatusFromQuery(ctx *gin.Context) (string, bool) {
+	status := ctx.Query("status")
+	switch status {
+	case "", model.StatusQuarantined, model.StatusRedriven:
+		return status, true
+	default:
+		return "", false
+	}
+}
+
+func getCurrentPageValue(ctx *gin.Context) int {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		logger.WithRequest(ctx).Errorln("Invalid page value; defaulting to 1:", err)
+		return 1
+	}
+	return page
+}
+
+func getItemPerPageValue(ctx *gin.Context) int {
+	perPage, err := strconv.Atoi(ctx.DefaultQuery("per_page", strconv.Itoa(constants.DefaultPerPageSize)))
+	if err != nil || perPage <= 0 || perPage > 100 {
+		logger.WithRequest(ctx).Errorln("Invalid per_page value; defaulting to:", constants.DefaultPerPageSize)
+		return constants.DefaultPerPageSize
+	}
+	return perPage
+}
+
+func getOffsetValue(page int, itemsPerPage int) int {
+	return (page - 1) * itemsPerPage
+}
+
+func calculateTotalPages(total, itemsPerPage int) int {
+	if itemsPerPage <= 0 {
+		return 1
+	}
+	return (total + itemsPerPage - 1) / itemsPerPage
+}
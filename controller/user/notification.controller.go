@@ -0,0 +1,72 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
+	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	model "github.com/swarajkumarsingh/turbo-deploy/models/user"
+)
+
+// get the email notification preferences of the authorized user
+func GetNotificationPreferences(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	uid, valid := getUserIdFromReq(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
+	}
+
+	prefs, err := model.GetNotificationPreferences(reqCtx, uid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.UserNotFoundMessage)
+		}
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, messages.FailedToRetrievePreferencesMessage)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":       false,
+		"preferences": prefs,
+	})
+}
+
+// opt in or out of email notifications per event type
+func UpdateNotificationPreferences(ctx *gin.Context) {
+	defer errorHandler.Recovery(ctx, http.StatusConflict)
+	reqCtx := ctx.Request.Context()
+
+	uid, valid := getUserIdFromReq(ctx)
+	if !valid {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
+	}
+
+	body, err := getNotificationPreferencesBody(ctx)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, err)
+	}
+
+	prefs, err := model.GetNotificationPreferences(reqCtx, uid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.UserNotFoundMessage)
+		}
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, messages.FailedToRetrievePreferencesMessage)
+	}
+
+	prefs = applyNotificationPreferences(prefs, body)
+	if err := model.UpsertNotificationPreferences(reqCtx, uid, prefs); err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusInternalServerError, err)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"error":       false,
+		"message":     "notification preferences updated successfully",
+		"preferences": prefs,
+	})
+}
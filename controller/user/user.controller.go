@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
 	"github.com/swarajkumarsingh/turbo-deploy/errorHandler"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/audit"
 	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
 	model "github.com/swarajkumarsingh/turbo-deploy/models/user"
 )
@@ -93,9 +94,17 @@ func UpdateUser(ctx *gin.Context) {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidBodyMessage)
 	}
 
+	before, err := model.GetUserById(context.TODO(), uid)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.UserNotFoundMessage)
+	}
+
 	if err = model.UpdateUser(context.TODO(), uid, body); err != nil {
 		logger.WithRequest(ctx).Panicln(err)
 	}
+	if after, err := model.GetUserById(context.TODO(), uid); err == nil {
+		audit.Record(ctx, "user.update", "user", uid, before, after)
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"error":   false,
@@ -112,9 +121,15 @@ func DeleteUser(ctx *gin.Context) {
 		logger.WithRequest(ctx).Panicln(http.StatusBadRequest, messages.InvalidUserIdMessage)
 	}
 
+	before, err := model.GetUserById(context.TODO(), uid)
+	if err != nil {
+		logger.WithRequest(ctx).Panicln(http.StatusNotFound, messages.UserNotFoundMessage)
+	}
+
 	if err := model.DeleteUser(context.TODO(), uid); err != nil {
 		logger.WithRequest(ctx).Panicln(err)
 	}
+	audit.Record(ctx, "user.delete", "user", uid, before, nil)
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"error":   false,
@@ -2,6 +2,7 @@ package user
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -30,6 +31,20 @@ func getUserIdFromParam(ctx *gin.Context) (int, bool) {
 	return uid, true
 }
 
+// getUserIdFromReq returns the id of the user authorized by the middleware
+func getUserIdFromReq(ctx *gin.Context) (int, bool) {
+	uid, valid := ctx.Get(constants.UserIdMiddlewareConstant)
+	if !valid || uid == nil {
+		return 0, false
+	}
+
+	id, err := general.IsInt(fmt.Sprintf("%v", uid))
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
 func getCreateUserBody(ctx *gin.Context) (model.UserBody, error) {
 	var body model.UserBody
 	if err := ctx.ShouldBindJSON(&body); err != nil {
@@ -80,3 +95,28 @@ func generateJwtToken(userId string) (string, error) {
 
 	return tokenString, nil
 }
+
+func getNotificationPreferencesBody(ctx *gin.Context) (model.NotificationPreferencesBody, error) {
+	var body model.NotificationPreferencesBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		return body, errors.New(messages.InvalidBodyMessage)
+	}
+	return body, nil
+}
+
+// applyNotificationPreferences overrides the current preferences with the fields present in the body
+func applyNotificationPreferences(prefs model.NotificationPreferences, body model.NotificationPreferencesBody) model.NotificationPreferences {
+	if body.DeploymentSuccess != nil {
+		prefs.DeploymentSuccess = *body.DeploymentSuccess
+	}
+	if body.DeploymentFailure != nil {
+		prefs.DeploymentFailure = *body.DeploymentFailure
+	}
+	if body.DeploymentCancelled != nil {
+		prefs.DeploymentCancelled = *body.DeploymentCancelled
+	}
+	if body.QuotaWarning != nil {
+		prefs.QuotaWarning = *body.QuotaWarning
+	}
+	return prefs
+}
@@ -0,0 +1,245 @@
+// Package archive extracts uploaded zip and tar.gz archives and packs directories back into tar.gz
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	Zip   = "zip"
+	TarGz = "tar.gz"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported archive format")
+	ErrUnsafePath        = errors.New("archive entry points outside the extraction directory")
+	ErrTooManyFiles      = errors.New("archive has too many files")
+	ErrTooLarge          = errors.New("archive is too large once extracted")
+)
+
+// Limits bound what an archive may extract to, they are checked against the bytes actually
+// written rather than the sizes the archive headers claim
+type Limits struct {
+	MaxFiles int
+	MaxBytes int64
+}
+
+// Format returns the archive format of a file name, or "" when it is not supported
+func Format(fileName string) string {
+	name := strings.ToLower(fileName)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return Zip
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return TarGz
+	}
+	return ""
+}
+
+// Extract writes the regular files and directories of the archive under dest, symlinks and other
+// special entries are skipped. Entries with absolute paths or .. segments fail the whole extraction
+func Extract(r io.ReaderAt, size int64, format string, dest string, limits Limits) error {
+	x := &extractor{dest: filepath.Clean(dest), limits: limits}
+	switch format {
+	case Zip:
+		return x.zip(r, size)
+	case TarGz:
+		return x.tarGz(io.NewSectionReader(r, 0, size))
+	}
+	return ErrUnsupportedFormat
+}
+
+type extractor struct {
+	dest    string
+	limits  Limits
+	files   int
+	written int64
+}
+
+func (x *extractor) zip(r io.ReaderAt, size int64) error {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+
+	for _, file := range reader.File {
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			if err := x.dir(file.Name); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			content, err := file.Open()
+			if err != nil {
+				return err
+			}
+			err = x.file(file.Name, content)
+			content.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (x *extractor) tarGz(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := x.dir(header.Name); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := x.file(header.Name, reader); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// target maps an entry name to its path under dest, rejecting names that would leave it
+func (x *extractor) target(name string) (string, error) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if name == "" || strings.HasPrefix(name, "/") || filepath.VolumeName(name) != "" || strings.Contains(name, ":") {
+		return "", ErrUnsafePath
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return "", ErrUnsafePath
+		}
+	}
+
+	target := filepath.Join(x.dest, filepath.FromSlash(path.Clean(name)))
+	if target != x.dest && !strings.HasPrefix(target, x.dest+string(os.PathSeparator)) {
+		return "", ErrUnsafePath
+	}
+	return target, nil
+}
+
+func (x *extractor) dir(name string) error {
+	target, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(target, 0o755)
+}
+
+func (x *extractor) file(name string, content io.Reader) error {
+	target, err := x.target(name)
+	if err != nil {
+		return err
+	}
+
+	if x.files++; x.files > x.limits.MaxFiles {
+		return ErrTooManyFiles
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	// O_EXCL keeps a later entry from overwriting an earlier one
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	remaining := x.limits.MaxBytes - x.written
+	n, err := io.Copy(out, io.LimitReader(content, remaining+1))
+	x.written += n
+	if err != nil {
+		return err
+	}
+	if x.written > x.limits.MaxBytes {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+// Root returns the directory the archive content starts at, archives of a single folder are
+// descended into so the folder name does not end up in every path
+func Root(dir string) (string, error) {
+	for {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return "", err
+		}
+		if len(entries) != 1 || !entries[0].IsDir() {
+			return dir, nil
+		}
+		dir = filepath.Join(dir, entries[0].Name())
+	}
+}
+
+// WriteTarGz packs the regular files under dir into w with paths relative to dir
+func WriteTarGz(dir string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.WalkDir(dir, func(file string, entry os.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     filepath.ToSlash(relative),
+			Size:     info.Size(),
+			Mode:     0o644,
+			ModTime:  info.ModTime(),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		content, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+		_, err = io.Copy(tw, content)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,208 @@
+// Package audit records who changed what. Middleware assigns every request an id and writes the audit events
+// of mutating requests once the handler finished; handlers describe their change with Record, requests without
+// recorded events get a generic event built from the route.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	model "github.com/swarajkumarsingh/turbo-deploy/models/audit"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+const eventsKey = "auditEvents"
+const writeTimeout = 2 * time.Second
+const redacted = "[redacted]"
+
+var requestIdRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// sensitiveKeys never reach the audit log, a change to them shows up as a redacted diff entry
+var sensitiveKeys = map[string]bool{"password": true, "password_hash": true, "secret": true, "token": true}
+
+type recordedEvent struct {
+	action       string
+	resourceType string
+	resourceId   string
+	before       interface{}
+	after        interface{}
+}
+
+// Middleware must run before the routes so it sees every request
+func Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestId := ctx.GetHeader(RequestIdHeader)
+		if !requestIdRegex.MatchString(requestId) {
+			requestId = uuid.NewString()
+		}
+		ctx.Set(constants.RequestIdMiddlewareConstant, requestId)
+		ctx.Header(RequestIdHeader, requestId)
+
+		ctx.Next()
+
+		events := buildEvents(ctx, requestId)
+		if len(events) == 0 {
+			return
+		}
+
+		writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx.Request.Context()), writeTimeout)
+		defer cancel()
+		if err := model.InsertAuditEvents(writeCtx, events); err != nil {
+			logger.WithRequest(ctx).Errorln("failed to write audit events:", err)
+		}
+	}
+}
+
+// Record describes the change made by the request, before is nil for creations and after nil for deletions
+func Record(ctx *gin.Context, action, resourceType string, resourceId interface{}, before, after interface{}) {
+	events, _ := ctx.Get(eventsKey)
+	recorded, _ := events.([]recordedEvent)
+	ctx.Set(eventsKey, append(recorded, recordedEvent{
+		action:       action,
+		resourceType: resourceType,
+		resourceId:   fmt.Sprint(resourceId),
+		before:       before,
+		after:        after,
+	}))
+}
+
+func buildEvents(ctx *gin.Context, requestId string) []model.AuditEvent {
+	events, _ := ctx.Get(eventsKey)
+	recorded, _ := events.([]recordedEvent)
+
+	if len(recorded) == 0 {
+		if !isMutating(ctx.Request.Method) || ctx.FullPath() == "" {
+			return nil
+		}
+		resourceType, resourceId := routeResource(ctx)
+		recorded = append(recorded, recordedEvent{
+			action:       ctx.Request.Method + " " + ctx.FullPath(),
+			resourceType: resourceType,
+			resourceId:   resourceId,
+		})
+	}
+
+	actorType, actorId := actor(ctx)
+	auditEvents := make([]model.AuditEvent, 0, len(recorded))
+	for _, event := range recorded {
+		before, after := toMap(event.before), toMap(event.after)
+		changes := diff(before, after)
+		auditEvents = append(auditEvents, model.AuditEvent{
+			ActorType:    actorType,
+			ActorId:      actorId,
+			Action:       event.action,
+			ResourceType: event.resourceType,
+			ResourceId:   event.resourceId,
+			Before:       toJSON(redact(before)),
+			After:        toJSON(redact(after)),
+			Diff:         toJSON(changes),
+			IP:           ctx.ClientIP(),
+			UserAgent:    ctx.Request.UserAgent(),
+			RequestId:    requestId,
+			Method:       ctx.Request.Method,
+			Path:         ctx.Request.URL.Path,
+			Status:       ctx.Writer.Status(),
+		})
+	}
+	return auditEvents
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// actor is the admin for requests that passed the admin api key, otherwise the authenticated user if any
+func actor(ctx *gin.Context) (string, string) {
+	if ctx.GetBool(constants.AdminMiddlewareConstant) {
+		return model.ActorAdmin, ""
+	}
+	if userId, ok := ctx.Get(constants.UserIdMiddlewareConstant); ok && fmt.Sprint(userId) != "" {
+		return model.ActorUser, fmt.Sprint(userId)
+	}
+	return model.ActorAnonymous, ""
+}
+
+// routeResource takes the segment before the first route parameter as the resource type, so
+// /project/:pid/webhooks/:wid is project :pid and /deployment is deployment without id
+func routeResource(ctx *gin.Context) (string, string) {
+	segments := strings.Split(strings.Trim(ctx.FullPath(), "/"), "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") && i > 0 {
+			return segments[i-1], ctx.Param(segment[1:])
+		}
+	}
+	return segments[0], ""
+}
+
+// toMap turns a model into its JSON fields so before and after can be compared field by field
+func toMap(value interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if value == nil {
+		return fields
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(raw, &fields)
+	return fields
+}
+
+func redact(fields map[string]interface{}) map[string]interface{} {
+	for key := range fields {
+		if sensitiveKeys[key] {
+			fields[key] = redacted
+		}
+	}
+	return fields
+}
+
+// diff lists the fields whose value changed as {"field": {"from": ..., "to": ...}}, it runs before redaction
+// so a changed secret is still detected and reported without its values
+func diff(before, after map[string]interface{}) map[string]interface{} {
+	changes := make(map[string]interface{})
+	for key, from := range before {
+		to, ok := after[key]
+		if !ok || !reflect.DeepEqual(from, to) {
+			changes[key] = change(key, from, to)
+		}
+	}
+	for key, to := range after {
+		if _, ok := before[key]; !ok {
+			changes[key] = change(key, nil, to)
+		}
+	}
+	return changes
+}
+
+func change(key string, from, to interface{}) map[string]interface{} {
+	if sensitiveKeys[key] {
+		return map[string]interface{}{"from": redacted, "to": redacted}
+	}
+	return map[string]interface{}{"from": from, "to": to}
+}
+
+func toJSON(fields map[string]interface{}) types.JSONText {
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return types.JSONText("{}")
+	}
+	return types.JSONText(raw)
+}
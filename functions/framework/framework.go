@@ -0,0 +1,234 @@
+// Package framework proposes build settings from the files of a repository directory. It does no io,
+// callers list the directory and read the entries Wanted asks for
+package framework
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	Next           = "next"
+	Vite           = "vite"
+	CreateReactApp = "create-react-app"
+	Angular        = "angular"
+	Astro          = "astro"
+	Hugo           = "hugo"
+	Node           = "node"
+	Static         = "static"
+)
+
+// Dir is a snapshot of a repository directory
+type Dir struct {
+	// Names are the entries of the directory, files and sub directories alike
+	Names []string
+	// Files holds the contents of the entries Wanted returned true for
+	Files map[string][]byte
+}
+
+// Settings are the suggested build settings, empty values keep the builder defaults
+type Settings struct {
+	InstallCommand string `json:"install_command"`
+	BuildCommand   string `json:"build_command"`
+	OutputDir      string `json:"output_dir"`
+}
+
+type Result struct {
+	Framework      string `json:"framework"`
+	PackageManager string `json:"package_manager,omitempty"`
+	Settings
+	// Warnings explain why the detected project may not deploy as is
+	Warnings []string `json:"warnings"`
+}
+
+type packageJson struct {
+	Scripts         map[string]string `json:"scripts"`
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+}
+
+func (p packageJson) has(dependency string) bool {
+	_, inDependencies := p.Dependencies[dependency]
+	_, inDevDependencies := p.DevDependencies[dependency]
+	return inDependencies || inDevDependencies
+}
+
+var (
+	nextConfigs     = []string{"next.config.js", "next.config.mjs", "next.config.cjs", "next.config.ts"}
+	viteConfigs     = []string{"vite.config.js", "vite.config.mjs", "vite.config.cjs", "vite.config.ts", "vite.config.mts"}
+	astroConfigs    = []string{"astro.config.mjs", "astro.config.js", "astro.config.cjs", "astro.config.ts", "astro.config.mts"}
+	hugoConfigs     = []string{"hugo.toml", "hugo.yaml", "hugo.json"}
+	nextExportRegex = regexp.MustCompile(`output\s*:\s*["'` + "`" + `]export["'` + "`" + `]`)
+)
+
+// Wanted reports whether Detect needs the contents of the entry name
+func Wanted(name string) bool {
+	return name == "package.json" || name == "angular.json" || contains(nextConfigs, name)
+}
+
+// Detect proposes build settings for the directory, ok is false when nothing deployable was recognised
+func Detect(dir Dir) (result Result, ok bool) {
+	result.Warnings = make([]string, 0)
+
+	raw, hasPackageJson := dir.Files["package.json"]
+	if !hasPackageJson {
+		switch {
+		case hasAny(dir, hugoConfigs...) || (hasAny(dir, "config.toml") && hasAny(dir, "content")):
+			result.Framework = Hugo
+			result.InstallCommand = "npm install --no-save hugo-extended"
+			result.BuildCommand = "npx hugo --minify"
+			result.OutputDir = "public"
+			return result, true
+		case hasAny(dir, "index.html"):
+			result.Framework = Static
+			return result, true
+		}
+		return result, false
+	}
+
+	var pkg packageJson
+	if err := json.Unmarshal(raw, &pkg); err != nil {
+		result.Warnings = append(result.Warnings, "package.json is not valid json")
+		return result, false
+	}
+
+	result.PackageManager, result.InstallCommand = packageManager(dir)
+	result.BuildCommand = result.PackageManager + " run build"
+	if pkg.Scripts["build"] == "" {
+		result.Warnings = append(result.Warnings, "package.json has no build script")
+	}
+
+	switch {
+	case pkg.has("next"):
+		result.Framework = Next
+		result.OutputDir = "out"
+		if !isNextExport(dir, pkg) {
+			result.Warnings = append(result.Warnings, "only static exports are served, set output: 'export' in next.config")
+		}
+	case pkg.has("@angular/core") || hasAny(dir, "angular.json"):
+		result.Framework = Angular
+		result.OutputDir = angularOutputDir(dir.Files["angular.json"])
+	case pkg.has("astro") || hasAny(dir, astroConfigs...):
+		// astro builds a static site into dist unless an ssr adapter is configured
+		result.Framework = Astro
+		result.OutputDir = "dist"
+	case pkg.has("vite") || hasAny(dir, viteConfigs...):
+		result.Framework = Vite
+		result.OutputDir = "dist"
+	case pkg.has("react-scripts"):
+		result.Framework = CreateReactApp
+		result.OutputDir = "build"
+	default:
+		// the builder looks for dist, build, public and release when no output dir is set
+		result.Framework = Node
+	}
+	return result, true
+}
+
+// packageManager picks the package manager from the lockfile, a lockfile also allows a clean install
+func packageManager(dir Dir) (name, install string) {
+	switch {
+	case hasAny(dir, "pnpm-lock.yaml"):
+		return "pnpm", "pnpm install --frozen-lockfile"
+	case hasAny(dir, "yarn.lock"):
+		return "yarn", "yarn install --frozen-lockfile"
+	case hasAny(dir, "package-lock.json", "npm-shrinkwrap.json"):
+		return "npm", "npm ci"
+	}
+	return "npm", "npm install"
+}
+
+// isNextExport reports whether the project builds a static export, through next.config or the
+// next export command of older versions
+func isNextExport(dir Dir, pkg packageJson) bool {
+	if strings.Contains(pkg.Scripts["build"], "next export") {
+		return true
+	}
+	for _, name := range nextConfigs {
+		if config, ok := dir.Files[name]; ok && nextExportRegex.Match(config) {
+			return true
+		}
+	}
+	return false
+}
+
+type angularWorkspace struct {
+	DefaultProject string `json:"defaultProject"`
+	Projects       map[string]struct {
+		Architect struct {
+			Build struct {
+				Builder string `json:"builder"`
+				Options struct {
+					OutputPath json.RawMessage `json:"outputPath"`
+				} `json:"options"`
+			} `json:"build"`
+		} `json:"architect"`
+	} `json:"projects"`
+}
+
+// angularOutputDir reads the build output path of the default project, the application builder
+// of Angular 17 and later writes the browser bundle to a browser sub directory
+func angularOutputDir(raw []byte) string {
+	var workspace angularWorkspace
+	if err := json.Unmarshal(raw, &workspace); err != nil || len(workspace.Projects) == 0 {
+		return "dist"
+	}
+
+	name := workspace.DefaultProject
+	if _, ok := workspace.Projects[name]; !ok {
+		names := make([]string, 0, len(workspace.Projects))
+		for projectName := range workspace.Projects {
+			names = append(names, projectName)
+		}
+		sort.Strings(names)
+		name = names[0]
+	}
+
+	build := workspace.Projects[name].Architect.Build
+	application := strings.HasSuffix(build.Builder, ":application")
+
+	var outputPath string
+	if err := json.Unmarshal(build.Options.OutputPath, &outputPath); err == nil && outputPath != "" {
+		if application {
+			return path.Join(outputPath, "browser")
+		}
+		return path.Clean(outputPath)
+	}
+
+	var outputPaths struct {
+		Base    string `json:"base"`
+		Browser string `json:"browser"`
+	}
+	if err := json.Unmarshal(build.Options.OutputPath, &outputPaths); err == nil && outputPaths.Base != "" {
+		if outputPaths.Browser == "" {
+			outputPaths.Browser = "browser"
+		}
+		return path.Join(outputPaths.Base, outputPaths.Browser)
+	}
+
+	if application {
+		return path.Join("dist", name, "browser")
+	}
+	return path.Join("dist", name)
+}
+
+func hasAny(dir Dir, names ...string) bool {
+	for _, name := range names {
+		if contains(dir.Names, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package framework
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// loadFixture reads a testdata repository the way the detect endpoint reads one through the source provider
+func loadFixture(t *testing.T, name string) Dir {
+	t.Helper()
+	root := filepath.Join("testdata", name)
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := Dir{Files: make(map[string][]byte)}
+	for _, entry := range entries {
+		dir.Names = append(dir.Names, entry.Name())
+		if entry.IsDir() || !Wanted(entry.Name()) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(root, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir.Files[entry.Name()] = content
+	}
+	return dir
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		fixture string
+		ok      bool
+		want    Result
+	}{
+		{
+			fixture: "next-export",
+			ok:      true,
+			want: Result{
+				Framework:      Next,
+				PackageManager: "npm",
+				Settings:       Settings{InstallCommand: "npm ci", BuildCommand: "npm run build", OutputDir: "out"},
+				Warnings:       []string{},
+			},
+		},
+		{
+			fixture: "next-server",
+			ok:      true,
+			want: Result{
+				Framework:      Next,
+				PackageManager: "yarn",
+				Settings:       Settings{InstallCommand: "yarn install --frozen-lockfile", BuildCommand: "yarn run build", OutputDir: "out"},
+				Warnings:       []string{"only static exports are served, set output: 'export' in next.config"},
+			},
+		},
+		{
+			fixture: "vite-pnpm",
+			ok:      true,
+			want: Result{
+				Framework:      Vite,
+				PackageManager: "pnpm",
+				Settings:       Settings{InstallCommand: "pnpm install --frozen-lockfile", BuildCommand: "pnpm run build", OutputDir: "dist"},
+				Warnings:       []string{},
+			},
+		},
+		{
+			fixture: "create-react-app",
+			ok:      true,
+			want: Result{
+				Framework:      CreateReactApp,
+				PackageManager: "yarn",
+				Settings:       Settings{InstallCommand: "yarn install --frozen-lockfile", BuildCommand: "yarn run build", OutputDir: "build"},
+				Warnings:       []string{},
+			},
+		},
+		{
+			fixture: "angular-application",
+			ok:      true,
+			want: Result{
+				Framework:      Angular,
+				PackageManager: "npm",
+				Settings:       Settings{InstallCommand: "npm ci", BuildCommand: "npm run build", OutputDir: "dist/shop/browser"},
+				Warnings:       []string{},
+			},
+		},
+		{
+			fixture: "angular-browser",
+			ok:      true,
+			want: Result{
+				Framework:      Angular,
+				PackageManager: "npm",
+				Settings:       Settings{InstallCommand: "npm install", BuildCommand: "npm run build", OutputDir: "dist/admin"},
+				Warnings:       []string{},
+			},
+		},
+		{
+			fixture: "astro",
+			ok:      true,
+			want: Result{
+				Framework:      Astro,
+				PackageManager: "npm",
+				Settings:       Settings{InstallCommand: "npm ci", BuildCommand: "npm run build", OutputDir: "dist"},
+				Warnings:       []string{},
+			},
+		},
+		{
+			fixture: "node",
+			ok:      true,
+			want: Result{
+				Framework:      Node,
+				PackageManager: "npm",
+				Settings:       Settings{InstallCommand: "npm install", BuildCommand: "npm run build"},
+				Warnings:       []string{},
+			},
+		},
+		{
+			fixture: "no-build-script",
+			ok:      true,
+			want: Result{
+				Framework:      Node,
+				PackageManager: "npm",
+				Settings:       Settings{InstallCommand: "npm install", BuildCommand: "npm run build"},
+				Warnings:       []string{"package.json has no build script"},
+			},
+		},
+		{
+			fixture: "invalid-package-json",
+			ok:      false,
+			want:    Result{Warnings: []string{"package.json is not valid json"}},
+		},
+		{
+			fixture: "hugo",
+			ok:      true,
+			want: Result{
+				Framework: Hugo,
+				Settings:  Settings{InstallCommand: "npm install --no-save hugo-extended", BuildCommand: "npx hugo --minify", OutputDir: "public"},
+				Warnings:  []string{},
+			},
+		},
+		{
+			fixture: "hugo-legacy",
+			ok:      true,
+			want: Result{
+				Framework: Hugo,
+				Settings:  Settings{InstallCommand: "npm install --no-save hugo-extended", BuildCommand: "npx hugo --minify", OutputDir: "public"},
+				Warnings:  []string{},
+			},
+		},
+		{
+			fixture: "static",
+			ok:      true,
+			want:    Result{Framework: Static, Warnings: []string{}},
+		},
+		{
+			fixture: "empty",
+			ok:      false,
+			want:    Result{Warnings: []string{}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			got, ok := Detect(loadFixture(t, test.fixture))
+			if ok != test.ok {
+				t.Fatalf("Detect() ok = %v, want %v", ok, test.ok)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("Detect() = %+v\nwant %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPackageManager(t *testing.T) {
+	tests := []struct {
+		lockfiles []string
+		name      string
+		install   string
+	}{
+		{nil, "npm", "npm install"},
+		{[]string{"package-lock.json"}, "npm", "npm ci"},
+		{[]string{"npm-shrinkwrap.json"}, "npm", "npm ci"},
+		{[]string{"yarn.lock"}, "yarn", "yarn install --frozen-lockfile"},
+		{[]string{"pnpm-lock.yaml"}, "pnpm", "pnpm install --frozen-lockfile"},
+		// a repository that switched package managers keeps the lockfile of the more specific one
+		{[]string{"package-lock.json", "yarn.lock"}, "yarn", "yarn install --frozen-lockfile"},
+		{[]string{"yarn.lock", "pnpm-lock.yaml"}, "pnpm", "pnpm install --frozen-lockfile"},
+	}
+
+	for _, test := range tests {
+		name, install := packageManager(Dir{Names: test.lockfiles})
+		if name != test.name || install != test.install {
+			t.Errorf("packageManager(%v) = %s, %s, want %s, %s", test.lockfiles, name, install, test.name, test.install)
+		}
+	}
+}
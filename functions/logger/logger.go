@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/swarajkumarsingh/turbo-deploy/conf"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
 	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
 	models "github.com/swarajkumarsingh/turbo-deploy/models/error"
 
@@ -74,6 +75,9 @@ func WithRequest(c *gin.Context) *Logger {
 	ctx := Log.With().Str("http_method", c.Request.Method)
 	ctx = ctx.Str("remote_addr", c.ClientIP())
 	ctx = ctx.Str("uri", c.FullPath())
+	if requestId := c.GetString(constants.RequestIdMiddlewareConstant); requestId != "" {
+		ctx = ctx.Str("request_id", requestId)
+	}
 	log := ctx.Logger()
 	return &Logger{&log}
 }
@@ -0,0 +1,78 @@
+// Package pagination implements keyset pagination: lists are ordered by id and a page resumes after the last id
+// of the previous one, the position is handed to clients as an opaque cursor
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
+	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
+)
+
+const cursorPrefix = "id:"
+
+// Params is the page requested with ?after=<cursor>&limit=
+type Params struct {
+	After int64
+	Limit int
+}
+
+// GetParams reads after and limit from the query, after accepts a cursor returned as next_cursor or a plain id
+func GetParams(ctx *gin.Context) (Params, error) {
+	params := Params{Limit: constants.DefaultPerPageSize}
+
+	if value := ctx.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > constants.MaxPageLimit {
+			return params, errors.New(messages.InvalidLimitMessage)
+		}
+		params.Limit = limit
+	}
+
+	if value := ctx.Query("after"); value != "" {
+		after, err := DecodeCursor(value)
+		if err != nil {
+			return params, errors.New(messages.InvalidCursorMessage)
+		}
+		params.After = after
+	}
+	return params, nil
+}
+
+// FetchLimit is the number of rows to query, one more than the page so the extra row tells whether another page exists
+func (p Params) FetchLimit() int {
+	return p.Limit + 1
+}
+
+// NextCursor is the cursor of the following page given the number of rows fetched with FetchLimit and the last id
+// returned, it is empty on the last page
+func (p Params) NextCursor(fetched int, lastId int64) string {
+	if fetched <= p.Limit {
+		return ""
+	}
+	return EncodeCursor(lastId)
+}
+
+func EncodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatInt(id, 10)))
+}
+
+func DecodeCursor(cursor string) (int64, error) {
+	if id, err := strconv.ParseInt(cursor, 10, 64); err == nil && id >= 0 {
+		return id, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, errors.New(messages.InvalidCursorMessage)
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(string(raw), cursorPrefix), 10, 64)
+	if err != nil || id < 0 {
+		return 0, errors.New(messages.InvalidCursorMessage)
+	}
+	return id, nil
+}
@@ -0,0 +1,128 @@
+// Package artifacts manages the build outputs the build server uploads to S3 under __outputs/<deployment id>/
+// and the source archives of uploaded deployments under __sources/<deployment id>/
+package artifacts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
+)
+
+// maxDeleteBatch is the most keys a single DeleteObjects call accepts
+const maxDeleteBatch = 1000
+
+// Reclaimed counts what a deletion removed
+type Reclaimed struct {
+	Objects int
+	Bytes   int64
+}
+
+func getS3Client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(
+		ctx,
+		config.WithRegion(constants.AWS_REGION),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(constants.AWS_ACCESS_KEY_ID, constants.AWS_SECRET_ACCESS_KEY, "")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return s3.NewFromConfig(cfg), nil
+}
+
+// DeploymentPrefix is the key prefix of every output of the deployment
+func DeploymentPrefix(deploymentId int) string {
+	return fmt.Sprintf("__outputs/%d/", deploymentId)
+}
+
+// DeleteDeploymentOutputs removes every build output of the deployment and its uploaded source, if any
+func DeleteDeploymentOutputs(ctx context.Context, deploymentId int) (Reclaimed, error) {
+	s3Client, err := getS3Client(ctx)
+	if err != nil {
+		return Reclaimed{}, err
+	}
+
+	var reclaimed Reclaimed
+	for _, prefix := range []string{DeploymentPrefix(deploymentId), SourcePrefix(deploymentId)} {
+		deleted, err := deletePrefix(ctx, s3Client, constants.TaskDefinitionS3BucketName, prefix)
+		reclaimed.Objects += deleted.Objects
+		reclaimed.Bytes += deleted.Bytes
+		if err != nil {
+			return reclaimed, fmt.Errorf("failed to delete S3 objects for deployment %d: %w", deploymentId, err)
+		}
+	}
+	return reclaimed, nil
+}
+
+// deletePrefix pages through every key under prefix and deletes each page with one DeleteObjects call,
+// deleting listed keys does not disturb the continuation token of the next page
+func deletePrefix(ctx context.Context, s3Client *s3.Client, bucketName, prefix string) (Reclaimed, error) {
+	var reclaimed Reclaimed
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(bucketName),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int32(maxDeleteBatch),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return reclaimed, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		batch, err := deleteBatch(ctx, s3Client, bucketName, page.Contents)
+		reclaimed.Objects += batch.Objects
+		reclaimed.Bytes += batch.Bytes
+		if err != nil {
+			return reclaimed, err
+		}
+	}
+	return reclaimed, nil
+}
+
+// deleteBatch deletes up to maxDeleteBatch objects, DeleteObjects reports failed keys in a successful response
+// so only the keys it did not report count as reclaimed
+func deleteBatch(ctx context.Context, s3Client *s3.Client, bucketName string, objects []s3Types.Object) (Reclaimed, error) {
+	var reclaimed Reclaimed
+	if len(objects) == 0 {
+		return reclaimed, nil
+	}
+
+	identifiers := make([]s3Types.ObjectIdentifier, 0, len(objects))
+	for _, object := range objects {
+		identifiers = append(identifiers, s3Types.ObjectIdentifier{Key: object.Key})
+	}
+
+	output, err := s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(bucketName),
+		Delete: &s3Types.Delete{
+			Objects: identifiers,
+			Quiet:   aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return reclaimed, fmt.Errorf("failed to delete objects: %w", err)
+	}
+
+	failed := make(map[string]bool, len(output.Errors))
+	for _, deleteError := range output.Errors {
+		failed[aws.ToString(deleteError.Key)] = true
+	}
+	for _, object := range objects {
+		if !failed[aws.ToString(object.Key)] {
+			reclaimed.Objects++
+			reclaimed.Bytes += aws.ToInt64(object.Size)
+		}
+	}
+
+	if len(output.Errors) > 0 {
+		first := output.Errors[0]
+		return reclaimed, fmt.Errorf("failed to delete %d objects, first %s: %s", len(output.Errors), aws.ToString(first.Key), aws.ToString(first.Message))
+	}
+	return reclaimed, nil
+}
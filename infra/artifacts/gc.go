@@ -0,0 +1,144 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+)
+
+// gcLockKey keeps replicas from collecting at the same time
+const gcLockKey int64 = 7_425_218_360_201
+
+// expiredQuery finds deployments whose outputs fall outside their project's retention policy. READY deployments
+// are ranked the way the proxy picks them, production first then newest built or promoted, so rank 1 is the
+// one it serves and retention_keep_ready is at least 1.
+const expiredQuery = `WITH ranked AS (
+		SELECT d.id, d.status, d.created_at, d.artifacts_deleted_at, p.retention_keep_ready, p.retention_fail_days,
+			ROW_NUMBER() OVER (PARTITION BY d.project_id, d.status ORDER BY (d.target = 'production') DESC,
+				COALESCE(d.promoted_at, d.created_at) DESC, d.id DESC) AS status_rank
+		FROM deployments d JOIN projects p ON p.id = d.project_id
+		WHERE d.deleted_at IS NULL AND p.deleted_at IS NULL AND d.status IN ('READY', 'FAIL')
+	)
+	SELECT id FROM ranked WHERE artifacts_deleted_at IS NULL AND id > $1 AND (
+		(status = 'READY' AND status_rank > retention_keep_ready) OR
+		(status = 'FAIL' AND created_at < NOW() - retention_fail_days * INTERVAL '1 day'))
+	ORDER BY id LIMIT $2`
+
+// Run summarises one collection, it is also stored in artifact_gc_runs
+type Run struct {
+	Deployments int
+	Failures    int
+	Reclaimed
+}
+
+// GC deletes build outputs that fall outside the retention policy of their project
+type GC struct {
+	db        *sqlx.DB
+	Interval  time.Duration
+	BatchSize int
+}
+
+func NewGC(db *sqlx.DB) *GC {
+	return &GC{
+		db:        db,
+		Interval:  constants.ArtifactGCInterval,
+		BatchSize: constants.ArtifactGCBatchSize,
+	}
+}
+
+// Run collects once at start and then every Interval until ctx is cancelled
+func (g *GC) Run(ctx context.Context) {
+	logger.Log.Println("Artifact GC started, interval:", g.Interval)
+	for {
+		run, err := g.Collect(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Log.Errorln("artifact gc:", err)
+		}
+		if run.Deployments > 0 {
+			logger.Log.Println(fmt.Sprintf("Artifact GC deleted %d objects of %d deployments, reclaimed %d bytes",
+				run.Objects, run.Deployments, run.Bytes))
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Log.Println("Artifact GC stopped")
+			return
+		case <-time.After(g.Interval):
+		}
+	}
+}
+
+// Collect deletes the outputs of every expired deployment and records the run. Deployments whose outputs
+// could not be deleted completely keep artifacts_deleted_at unset and are retried by the next run.
+func (g *GC) Collect(ctx context.Context) (Run, error) {
+	var run Run
+
+	conn, err := g.db.Connx(ctx)
+	if err != nil {
+		return run, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.GetContext(ctx, &locked, "SELECT pg_try_advisory_lock($1)", gcLockKey); err != nil {
+		return run, fmt.Errorf("failed to acquire gc lock: %w", err)
+	}
+	if !locked {
+		return run, nil
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", gcLockKey)
+
+	var runId int64
+	if err := conn.GetContext(ctx, &runId, "INSERT INTO artifact_gc_runs DEFAULT VALUES RETURNING id"); err != nil {
+		return run, fmt.Errorf("failed to record gc run: %w", err)
+	}
+
+	err = g.collect(ctx, conn, &run)
+
+	errorMessage := ""
+	if err != nil {
+		errorMessage = err.Error()
+	}
+	query := `UPDATE artifact_gc_runs SET deployments = $2, objects = $3, reclaimed_bytes = $4, failures = $5, error = $6,
+		finished_at = NOW() WHERE id = $1`
+	if _, updateErr := conn.ExecContext(context.WithoutCancel(ctx), query, runId, run.Deployments, run.Objects, run.Bytes, run.Failures, errorMessage); updateErr != nil {
+		logger.Log.Errorln("artifact gc: failed to record run", runId, updateErr)
+	}
+	return run, err
+}
+
+func (g *GC) collect(ctx context.Context, conn *sqlx.Conn, run *Run) error {
+	afterId := 0
+	for {
+		var ids []int
+		if err := conn.SelectContext(ctx, &ids, expiredQuery, afterId, g.BatchSize); err != nil {
+			return fmt.Errorf("failed to load expired deployments: %w", err)
+		}
+
+		for _, id := range ids {
+			afterId = id
+			reclaimed, err := DeleteDeploymentOutputs(ctx, id)
+			run.Objects += reclaimed.Objects
+			run.Bytes += reclaimed.Bytes
+			if err != nil {
+				run.Failures++
+				logger.Log.Errorln("artifact gc:", err)
+				continue
+			}
+
+			query := `UPDATE deployments SET artifacts_deleted_at = NOW(), artifacts_reclaimed_bytes = artifacts_reclaimed_bytes + $2 WHERE id = $1`
+			if _, err := conn.ExecContext(ctx, query, id, reclaimed.Bytes); err != nil {
+				return fmt.Errorf("failed to mark outputs of deployment %d deleted: %w", id, err)
+			}
+			run.Deployments++
+		}
+
+		if len(ids) < g.BatchSize {
+			return nil
+		}
+	}
+}
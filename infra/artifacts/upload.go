@@ -0,0 +1,176 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
+)
+
+// uploadConcurrency matches the queue concurrency of the build server
+const uploadConcurrency = 5
+
+// SourcePrefix is the key prefix of the source archive of an uploaded deployment, the builder
+// downloads it instead of cloning a repository
+func SourcePrefix(deploymentId int) string {
+	return fmt.Sprintf("__sources/%d/", deploymentId)
+}
+
+// UploadDeploymentOutputs uploads every file under dir as a build output of the deployment, skip
+// leaves out files by their slash separated path relative to dir and uploaded is called after each file
+func UploadDeploymentOutputs(ctx context.Context, deploymentId int, dir string, skip func(relative string) bool,
+	uploaded func(relative string)) (int, error) {
+	s3Client, err := getS3Client(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	files := make([]string, 0)
+	err = filepath.WalkDir(dir, func(file string, entry os.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		relative, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		if relative = filepath.ToSlash(relative); !skip(relative) {
+			files = append(files, relative)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		count    int
+	)
+	work := make(chan string)
+	for i := 0; i < uploadConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for relative := range work {
+				err := putFile(ctx, s3Client, DeploymentPrefix(deploymentId)+relative, filepath.Join(dir, filepath.FromSlash(relative)))
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("failed to upload %s: %w", relative, err)
+					cancel()
+				}
+				if err == nil {
+					count++
+				}
+				mu.Unlock()
+
+				if err == nil {
+					uploaded(relative)
+				}
+			}
+		}()
+	}
+
+	for _, relative := range files {
+		select {
+		case work <- relative:
+		case <-ctx.Done():
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	return count, firstErr
+}
+
+func putFile(ctx context.Context, s3Client *s3.Client, key, file string) error {
+	content, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(file))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(constants.TaskDefinitionS3BucketName),
+		Key:         aws.String(key),
+		Body:        content,
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+// UploadSourceArchive stores the source archive of the deployment and returns a presigned url the
+// builder can download it from for ttl
+func UploadSourceArchive(ctx context.Context, deploymentId int, archive io.ReadSeeker, ttl time.Duration) (string, error) {
+	s3Client, err := getS3Client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(constants.TaskDefinitionS3BucketName),
+		Key:         aws.String(sourceArchiveKey(deploymentId)),
+		Body:        archive,
+		ContentType: aws.String("application/gzip"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload source archive for deployment %d: %w", deploymentId, err)
+	}
+	return presignSourceArchive(ctx, s3Client, deploymentId, ttl)
+}
+
+// CopySourceArchive gives a redeployment its own copy of the source archive, so the archive outlives
+// the retention of the deployment it was uploaded to, and returns a presigned url for it
+func CopySourceArchive(ctx context.Context, fromDeploymentId, toDeploymentId int, ttl time.Duration) (string, error) {
+	s3Client, err := getS3Client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s3Client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(constants.TaskDefinitionS3BucketName),
+		CopySource: aws.String(constants.TaskDefinitionS3BucketName + "/" + sourceArchiveKey(fromDeploymentId)),
+		Key:        aws.String(sourceArchiveKey(toDeploymentId)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to copy source archive of deployment %d: %w", fromDeploymentId, err)
+	}
+	return presignSourceArchive(ctx, s3Client, toDeploymentId, ttl)
+}
+
+func sourceArchiveKey(deploymentId int) string {
+	return SourcePrefix(deploymentId) + "source.tar.gz"
+}
+
+func presignSourceArchive(ctx context.Context, s3Client *s3.Client, deploymentId int, ttl time.Duration) (string, error) {
+	request, err := s3.NewPresignClient(s3Client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(constants.TaskDefinitionS3BucketName),
+		Key:    aws.String(sourceArchiveKey(deploymentId)),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign source archive for deployment %d: %w", deploymentId, err)
+	}
+	return request.URL, nil
+}
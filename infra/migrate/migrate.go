@@ -0,0 +1,365 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+)
+
+// lockKey identifies the advisory lock held while migrating, so replicas starting together run migrations once
+const lockKey int64 = 7_425_218_360_199
+
+// fileNameRegex matches <20 digit version>_<name>.sql and its <version>_<name>.down.sql counterpart
+var fileNameRegex = regexp.MustCompile(`^(\d{20})_(\w+?)(\.down)?\.sql$`)
+
+var ErrChecksumMismatch = errors.New("applied migrations were modified")
+
+// Migration is an up script with its optional down script
+type Migration struct {
+	Version  string
+	Name     string
+	UpSQL    string
+	DownSQL  string
+	Checksum string
+}
+
+// Status describes a migration found on disk or recorded as applied
+type Status struct {
+	Version   string     `db:"version"`
+	Name      string     `db:"name"`
+	Applied   bool       `db:"-"`
+	AppliedAt *time.Time `db:"applied_at"`
+	Checksum  string     `db:"checksum"`
+	// Modified is set when the file changed after it was applied, Missing when the file no longer exists
+	Modified bool `db:"-"`
+	Missing  bool `db:"-"`
+}
+
+type Migrator struct {
+	db  *sqlx.DB
+	dir string
+}
+
+func New(db *sqlx.DB, dir string) *Migrator {
+	return &Migrator{db: db, dir: dir}
+}
+
+// Load reads the migrations in dir ordered by version
+func Load(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[string]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := fileNameRegex.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s, expected <20 digit version>_<name>.sql", entry.Name())
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		version, name, down := match[1], match[2], match[3] != ""
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %s has files with different names: %s and %s", version, migration.Name, name)
+		}
+		if down {
+			migration.DownSQL = string(content)
+			continue
+		}
+		migration.UpSQL = string(content)
+		migration.Checksum = checksum(content)
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Checksum == "" {
+			return nil, fmt.Errorf("migration %s_%s has a down script but no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Status lists every migration on disk and every applied one, ordered by version
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sqlx.Conn, migrations []Migration) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		statuses = compare(migrations, applied)
+		return nil
+	})
+	return statuses, err
+}
+
+// Up applies every pending migration in version order, including ones older than the latest applied migration
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.To(ctx, "")
+}
+
+// Down reverts the last steps applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sqlx.Conn, migrations []Migration) error {
+		applied, err := verifiedApplied(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			if _, ok := applied[migrations[i].Version]; !ok {
+				continue
+			}
+			if err := revert(ctx, conn, migrations[i]); err != nil {
+				return err
+			}
+			reverted = append(reverted, migrations[i])
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// To migrates up or down until exactly the migrations up to version are applied, an empty version applies all
+func (m *Migrator) To(ctx context.Context, version string) ([]Migration, error) {
+	var changed []Migration
+	err := m.withLock(ctx, func(conn *sqlx.Conn, migrations []Migration) error {
+		if version != "" && !hasVersion(migrations, version) {
+			return fmt.Errorf("unknown migration version %s", version)
+		}
+
+		applied, err := verifiedApplied(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+
+		// revert newer migrations first, newest to oldest
+		for i := len(migrations) - 1; i >= 0 && version != ""; i-- {
+			if _, ok := applied[migrations[i].Version]; !ok || migrations[i].Version <= version {
+				continue
+			}
+			if err := revert(ctx, conn, migrations[i]); err != nil {
+				return err
+			}
+			changed = append(changed, migrations[i])
+		}
+
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok || (version != "" && migration.Version > version) {
+				continue
+			}
+			if err := apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			changed = append(changed, migration)
+		}
+		return nil
+	})
+	return changed, err
+}
+
+// withLock runs fn on a single connection holding the advisory lock, advisory locks belong to the session
+// so the lock and the migrations must share the connection
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn, migrations []Migration) error) error {
+	migrations, err := Load(m.dir)
+	if err != nil {
+		return err
+	}
+
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// the lock is released with the session anyway, so a failed unlock only delays other replicas
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			logger.Log.Errorln("failed to release migration lock:", err)
+		}
+	}()
+
+	if err := ensureTable(ctx, conn, migrations); err != nil {
+		return err
+	}
+	return fn(conn, migrations)
+}
+
+// ensureTable creates schema_migrations, adopting the progress recorded by the previous migration runner
+// in migrations_metadata so existing databases do not re-run their scripts
+func ensureTable(ctx context.Context, conn *sqlx.Conn, migrations []Migration) error {
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(20) PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum VARCHAR(64) NOT NULL,
+		applied_at TIMESTAMP DEFAULT NOW() NOT NULL
+	)`
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var count int
+	if err := conn.GetContext(ctx, &count, "SELECT COUNT(*) FROM schema_migrations"); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var legacy sql.NullString
+	err := conn.GetContext(ctx, &legacy, `SELECT script_name FROM migrations_metadata ORDER BY script_name DESC LIMIT 1`)
+	if err != nil {
+		// a fresh database has no migrations_metadata table
+		return nil
+	}
+	match := regexp.MustCompile(`(\d{20})_`).FindStringSubmatch(legacy.String)
+	if match == nil {
+		return nil
+	}
+
+	for _, migration := range migrations {
+		if migration.Version > match[1] {
+			break
+		}
+		if _, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations(version, name, checksum) VALUES($1, $2, $3)`,
+			migration.Version, migration.Name, migration.Checksum); err != nil {
+			return fmt.Errorf("failed to adopt legacy migration %s: %w", migration.Version, err)
+		}
+	}
+	logger.Log.Println("Adopted migrations up to", match[1], "from migrations_metadata")
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, conn *sqlx.Conn) (map[string]Status, error) {
+	var rows []Status
+	if err := conn.SelectContext(ctx, &rows, "SELECT version, name, checksum, applied_at FROM schema_migrations"); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]Status, len(rows))
+	for _, row := range rows {
+		row.Applied = true
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// verifiedApplied returns the applied migrations after checking none of their files changed since
+func verifiedApplied(ctx context.Context, conn *sqlx.Conn, migrations []Migration) (map[string]Status, error) {
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var modified []string
+	for _, status := range compare(migrations, applied) {
+		if status.Modified {
+			modified = append(modified, status.Version+"_"+status.Name)
+		}
+	}
+	if len(modified) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, strings.Join(modified, ", "))
+	}
+	return applied, nil
+}
+
+func compare(migrations []Migration, applied map[string]Status) []Status {
+	statuses := make([]Status, 0, len(migrations))
+	seen := make(map[string]bool, len(migrations))
+	for _, migration := range migrations {
+		seen[migration.Version] = true
+		status := Status{Version: migration.Version, Name: migration.Name, Checksum: migration.Checksum}
+		if row, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.AppliedAt
+			status.Modified = row.Checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	for version, row := range applied {
+		if !seen[version] {
+			row.Missing = true
+			statuses = append(statuses, row)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses
+}
+
+func hasVersion(migrations []Migration, version string) bool {
+	for _, migration := range migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+func apply(ctx context.Context, conn *sqlx.Conn, migration Migration) error {
+	logger.Log.Println("Applying migration:", migration.Version, migration.Name)
+	return inTx(ctx, conn, migration, migration.UpSQL,
+		`INSERT INTO schema_migrations(version, name, checksum) VALUES($1, $2, $3)`,
+		migration.Version, migration.Name, migration.Checksum)
+}
+
+func revert(ctx context.Context, conn *sqlx.Conn, migration Migration) error {
+	if strings.TrimSpace(migration.DownSQL) == "" {
+		return fmt.Errorf("migration %s_%s has no down script", migration.Version, migration.Name)
+	}
+	logger.Log.Println("Reverting migration:", migration.Version, migration.Name)
+	return inTx(ctx, conn, migration, migration.DownSQL, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+}
+
+// inTx runs the script and its bookkeeping statement in one transaction
+func inTx(ctx context.Context, conn *sqlx.Conn, migration Migration, script, bookkeeping string, args ...interface{}) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %s: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %s_%s failed: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", migration.Version, err)
+	}
+	return tx.Commit()
+}
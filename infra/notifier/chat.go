@@ -0,0 +1,46 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Chat posts a plain {"text": ...} message, the format accepted by Mattermost, Rocket.Chat,
+// Google Chat and most other incoming webhooks
+type Chat struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+func (c *Chat) Notify(ctx context.Context, event Event) error {
+	return postJSON(ctx, c.Client, c.WebhookURL, map[string]string{"text": plainText(event)})
+}
+
+// plainText renders the event as a few lines of text
+func plainText(event Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (deployment #%d)\n", event.Title(), event.DeploymentId)
+	if duration := formatDuration(event.Duration); duration != "" {
+		fmt.Fprintf(&b, "Duration: %s\n", duration)
+	}
+	if event.Branch != "" {
+		fmt.Fprintf(&b, "Branch: %s\n", event.Branch)
+	}
+	if event.CommitSha != "" {
+		fmt.Fprintf(&b, "Commit: %s %s %s\n", event.ShortSha(), event.CommitAuthor, firstLine(event.CommitMessage))
+	}
+	if event.ReadyUrl != "" {
+		fmt.Fprintf(&b, "URL: %s\n", event.ReadyUrl)
+	}
+	if event.ErrorLog != "" {
+		fmt.Fprintf(&b, "Last error:\n%s\n", truncate(event.ErrorLog, 2000))
+	}
+	return b.String()
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
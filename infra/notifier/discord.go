@@ -0,0 +1,78 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	discordColorSuccess = 0x2ecc71
+	discordColorFailure = 0xe74c3c
+	// discordFieldLimit and discordDescriptionLimit leave room for the code fences within the embed limits
+	discordFieldLimit       = 1000
+	discordDescriptionLimit = 4000
+)
+
+// Discord posts embeds to a channel webhook
+type Discord struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	URL         string         `json:"url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields"`
+	Timestamp   string         `json:"timestamp"`
+}
+
+type discordMessage struct {
+	Embeds []discordEmbed `json:"embeds"`
+}
+
+func (d *Discord) Notify(ctx context.Context, event Event) error {
+	return postJSON(ctx, d.Client, d.WebhookURL, discordPayload(event))
+}
+
+func discordPayload(event Event) discordMessage {
+	color := discordColorSuccess
+	if !event.Succeeded() {
+		color = discordColorFailure
+	}
+
+	fields := []discordField{
+		{Name: "Project", Value: event.ProjectName, Inline: true},
+		{Name: "Deployment", Value: fmt.Sprintf("#%d", event.DeploymentId), Inline: true},
+	}
+	if duration := formatDuration(event.Duration); duration != "" {
+		fields = append(fields, discordField{Name: "Duration", Value: duration, Inline: true})
+	}
+	if event.Branch != "" {
+		fields = append(fields, discordField{Name: "Branch", Value: "`" + event.Branch + "`", Inline: true})
+	}
+	if event.CommitSha != "" {
+		fields = append(fields, discordField{Name: "Commit", Value: fmt.Sprintf("`%s` %s", event.ShortSha(), event.CommitAuthor), Inline: true})
+	}
+	if event.ErrorLog != "" {
+		fields = append(fields, discordField{Name: "Last error", Value: "```" + truncate(event.ErrorLog, discordFieldLimit) + "```"})
+	}
+
+	return discordMessage{Embeds: []discordEmbed{{
+		Title:       event.Title(),
+		URL:         event.ReadyUrl,
+		Description: truncate(event.CommitMessage, discordDescriptionLimit),
+		Color:       color,
+		Fields:      fields,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}}}
+}
@@ -0,0 +1,191 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/webhook"
+)
+
+const (
+	TypeSlack   = "slack"
+	TypeDiscord = "discord"
+	TypeChat    = "chat"
+	TypeEmail   = "email"
+)
+
+const (
+	StatusReady = "READY"
+	StatusFail  = "FAIL"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Types are the supported channel types
+var Types = []string{TypeSlack, TypeDiscord, TypeChat, TypeEmail}
+
+// Event describes a deployment that reached a terminal status, empty fields are left out of the message
+type Event struct {
+	ProjectId     int
+	ProjectName   string
+	DeploymentId  int
+	Status        string
+	ReadyUrl      string
+	Duration      time.Duration
+	Branch        string
+	CommitSha     string
+	CommitMessage string
+	CommitAuthor  string
+	// ErrorLog is the last error logged by the build, only set on failure
+	ErrorLog string
+}
+
+func (e Event) Succeeded() bool {
+	return e.Status == StatusReady
+}
+
+func (e Event) Title() string {
+	if e.Succeeded() {
+		return fmt.Sprintf("Deployment of %s succeeded", e.ProjectName)
+	}
+	return fmt.Sprintf("Deployment of %s failed", e.ProjectName)
+}
+
+// ShortSha returns the first 7 characters of the commit
+func (e Event) ShortSha() string {
+	if len(e.CommitSha) > 7 {
+		return e.CommitSha[:7]
+	}
+	return e.CommitSha
+}
+
+// Notifier sends a deployment event to a single destination
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Channel is a destination configured on a project
+type Channel struct {
+	Id        int    `db:"id"`
+	ProjectId int    `db:"project_id"`
+	Type      string `db:"type"`
+	Target    string `db:"target"`
+	OnSuccess bool   `db:"on_success"`
+	OnFailure bool   `db:"on_failure"`
+}
+
+// ValidType reports whether t is one of Types
+func ValidType(t string) bool {
+	for _, valid := range Types {
+		if valid == t {
+			return true
+		}
+	}
+	return false
+}
+
+// New returns the notifier for a webhook channel, email channels are sent by the email consumer together with
+// the deployment emails of the project owner
+func New(channel Channel, client *http.Client) (Notifier, error) {
+	switch channel.Type {
+	case TypeSlack:
+		return &Slack{WebhookURL: channel.Target, Client: client}, nil
+	case TypeDiscord:
+		return &Discord{WebhookURL: channel.Target, Client: client}, nil
+	case TypeChat:
+		return &Chat{WebhookURL: channel.Target, Client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown channel type %q", channel.Type)
+	}
+}
+
+// Router sends events to the webhook channels configured on their project
+type Router struct {
+	db     sqlx.QueryerContext
+	client *http.Client
+}
+
+// NewRouter creates a router whose client refuses internal addresses like webhook deliveries do
+func NewRouter(db sqlx.QueryerContext) *Router {
+	return &Router{db: db, client: webhook.NewClient(defaultTimeout)}
+}
+
+// Route notifies every active webhook channel of the project that wants the event, a failing channel does not
+// stop the others
+func (r *Router) Route(ctx context.Context, event Event) error {
+	var channels []Channel
+	query := `SELECT id, project_id, type, target, on_success, on_failure FROM notification_channels
+		WHERE project_id = $1 AND type <> 'email' AND active AND (CASE WHEN $2 THEN on_success ELSE on_failure END)`
+	if err := sqlx.SelectContext(ctx, r.db, &channels, query, event.ProjectId, event.Succeeded()); err != nil {
+		return fmt.Errorf("failed to load notification channels: %w", err)
+	}
+
+	var errs []error
+	for _, channel := range channels {
+		n, err := New(channel, r.client)
+		if err == nil {
+			err = n.Notify(ctx, event)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s channel %d: %w", channel.Type, channel.Id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// postJSON sends body to url and fails on any non 2xx response
+func postJSON(ctx context.Context, client *http.Client, url string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if client == nil {
+		client = webhook.NewClient(defaultTimeout)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, message)
+	}
+	return nil
+}
+
+// truncate shortens s to at most max bytes on a rune boundary, keeping the end which holds the actual error
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[len(s)-max+len("…"):]
+	for len(s) > 0 && !utf8.RuneStart(s[0]) {
+		s = s[1:]
+	}
+	return "…" + s
+}
+
+// formatDuration renders durations like 1m5s, it is empty when the build time is unknown
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return d.Round(time.Second).String()
+}
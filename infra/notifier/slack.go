@@ -0,0 +1,84 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// slackTextLimit keeps section text under the 3000 character limit of Block Kit
+const slackTextLimit = 2900
+
+// Slack posts Block Kit messages to an incoming webhook
+type Slack struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string       `json:"type"`
+	Text     *slackText   `json:"text,omitempty"`
+	Fields   []slackText  `json:"fields,omitempty"`
+	Elements []slackBlock `json:"elements,omitempty"`
+	// button elements
+	URL   string `json:"url,omitempty"`
+	Style string `json:"style,omitempty"`
+}
+
+type slackMessage struct {
+	// Text is shown in notifications where blocks are not rendered
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+func (s *Slack) Notify(ctx context.Context, event Event) error {
+	return postJSON(ctx, s.Client, s.WebhookURL, slackPayload(event))
+}
+
+func slackPayload(event Event) slackMessage {
+	icon := ":white_check_mark:"
+	if !event.Succeeded() {
+		icon = ":x:"
+	}
+
+	fields := []slackText{
+		{Type: "mrkdwn", Text: fmt.Sprintf("*Project*\n%s", event.ProjectName)},
+		{Type: "mrkdwn", Text: fmt.Sprintf("*Deployment*\n#%d", event.DeploymentId)},
+	}
+	if duration := formatDuration(event.Duration); duration != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Duration*\n%s", duration)})
+	}
+	if event.Branch != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Branch*\n`%s`", event.Branch)})
+	}
+	if event.CommitSha != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Commit*\n`%s` %s", event.ShortSha(), event.CommitAuthor)})
+	}
+
+	blocks := []slackBlock{
+		{Type: "header", Text: &slackText{Type: "plain_text", Text: event.Title()}},
+		{Type: "section", Fields: fields},
+	}
+	if event.CommitMessage != "" {
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncate(event.CommitMessage, slackTextLimit)}})
+	}
+	if event.ErrorLog != "" {
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn",
+			Text: "*Last error*\n```" + truncate(event.ErrorLog, slackTextLimit) + "```"}})
+	}
+	if event.ReadyUrl != "" {
+		blocks = append(blocks, slackBlock{Type: "actions", Elements: []slackBlock{{
+			Type:  "button",
+			Text:  &slackText{Type: "plain_text", Text: "Visit deployment"},
+			URL:   event.ReadyUrl,
+			Style: "primary",
+		}}})
+	}
+
+	return slackMessage{Text: fmt.Sprintf("%s %s", icon, event.Title()), Blocks: blocks}
+}
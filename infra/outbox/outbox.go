@@ -0,0 +1,61 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const AggregateDeployment = "deployment"
+
+const EventDeploymentQueued = "deployment.queued"
+
+// Message is an event recorded in the same transaction as the state change it describes
+type Message struct {
+	AggregateType string
+	AggregateId   string
+	EventType     string
+	// DedupId identifies the event across retries, it is sent as the SQS deduplication id
+	// and a second insert with the same id is ignored
+	DedupId string
+	Payload interface{}
+}
+
+// Record is a stored message as read by the relay
+type Record struct {
+	Id            int64           `json:"id" db:"id"`
+	AggregateType string          `json:"aggregate_type" db:"aggregate_type"`
+	AggregateId   string          `json:"aggregate_id" db:"aggregate_id"`
+	EventType     string          `json:"event_type" db:"event_type"`
+	DedupId       string          `json:"dedup_id" db:"dedup_id"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Attempts      int             `json:"-" db:"attempts"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+// execer is satisfied by *sql.Tx and *sqlx.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Insert records msg, tx must be the transaction of the state change so both commit or neither does
+func Insert(ctx context.Context, tx execer, msg Message) error {
+	payload, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox payload: %w", err)
+	}
+
+	query := `INSERT INTO outbox(aggregate_type, aggregate_id, event_type, dedup_id, payload) VALUES($1, $2, $3, $4, $5)
+		ON CONFLICT (dedup_id) DO NOTHING`
+	if _, err := tx.ExecContext(ctx, query, msg.AggregateType, msg.AggregateId, msg.EventType, msg.DedupId, payload); err != nil {
+		return fmt.Errorf("failed to insert outbox message: %w", err)
+	}
+	return nil
+}
+
+// DeploymentDedupId identifies a deployment reaching a status, a deployment reaches each status at most once
+func DeploymentDedupId(deploymentId int, status string) string {
+	return fmt.Sprintf("deployment-%d-%s", deploymentId, status)
+}
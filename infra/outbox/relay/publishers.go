@@ -0,0 +1,71 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/outbox"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/redis"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/webhook"
+)
+
+// SQSPublisher sends records to a FIFO queue, ordered per aggregate and de-duplicated on the dedup id
+type SQSPublisher struct {
+	QueueURL string
+}
+
+func (p *SQSPublisher) Publish(_ context.Context, record outbox.Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	groupId := record.AggregateType + "-" + record.AggregateId
+	return sqs.SendFIFOMessage(record.DedupId, string(body), groupId, p.QueueURL)
+}
+
+// RedisPublisher publishes records on a pub/sub channel for live subscribers such as SSE streams,
+// subscribers that are not connected miss the record
+type RedisPublisher struct {
+	Channel string
+}
+
+func (p *RedisPublisher) Publish(_ context.Context, record outbox.Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return redis.Publish(p.Channel, string(body))
+}
+
+// WebhookPublisher delivers deployment events to the webhooks of their project. Failed deliveries are recorded
+// and can be redelivered from the api, so only a failure to load the webhooks makes the record retry, a retry
+// would send the event again to the webhooks that did receive it
+type WebhookPublisher struct {
+	Dispatcher *webhook.Dispatcher
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, record outbox.Record) error {
+	if record.AggregateType != outbox.AggregateDeployment || !webhook.ValidEvent(record.EventType) {
+		return nil
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(record.Payload, &data); err != nil {
+		return err
+	}
+	projectId, ok := data["project_id"].(float64)
+	if !ok {
+		return fmt.Errorf("outbox record %d has no project_id", record.Id)
+	}
+
+	err := p.Dispatcher.Dispatch(ctx, int(projectId), record.EventType, data)
+	if errors.Is(err, webhook.ErrDeliveryFailed) {
+		logger.Log.Warnln(fmt.Sprintf("webhook deliveries of outbox record %d failed: %v", record.Id, err))
+		return nil
+	}
+	return err
+}
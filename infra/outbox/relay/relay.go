@@ -0,0 +1,184 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/outbox"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+	maxBackoff          = 5 * time.Minute
+	maxErrorLen         = 1024
+	// published records are kept for a while to debug deliveries, then pruned in batches
+	defaultRetention     = 7 * 24 * time.Hour
+	defaultPruneInterval = time.Hour
+	pruneBatchSize       = 1000
+)
+
+// Publisher sends an outbox record downstream, it may be called more than once for the same record
+type Publisher interface {
+	Publish(ctx context.Context, record outbox.Record) error
+}
+
+// Relay publishes unpublished outbox records in order. A record is marked published only after every publisher
+// accepted it, so delivery is at-least-once and consumers de-duplicate on the dedup id.
+type Relay struct {
+	db            *sqlx.DB
+	publishers    []Publisher
+	BatchSize     int
+	PollInterval  time.Duration
+	Retention     time.Duration
+	PruneInterval time.Duration
+}
+
+func New(db *sqlx.DB, publishers ...Publisher) *Relay {
+	return &Relay{
+		db:            db,
+		publishers:    publishers,
+		BatchSize:     defaultBatchSize,
+		PollInterval:  defaultPollInterval,
+		Retention:     defaultRetention,
+		PruneInterval: defaultPruneInterval,
+	}
+}
+
+// Run relays records until ctx is cancelled, full batches are followed immediately by the next one. Published
+// records older than Retention are pruned every PruneInterval.
+func (r *Relay) Run(ctx context.Context) {
+	logger.Log.Printf("Outbox relay started with %d publishers", len(r.publishers))
+	var lastPrune time.Time
+	for {
+		if time.Since(lastPrune) >= r.PruneInterval {
+			lastPrune = time.Now()
+			pruned, err := r.prune(ctx)
+			if err != nil && ctx.Err() == nil {
+				logger.Log.Errorln("outbox relay:", err)
+			}
+			if pruned > 0 {
+				logger.Log.Println(fmt.Sprintf("Pruned %d published outbox records", pruned))
+			}
+		}
+
+		relayed, err := r.relayBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Log.Errorln("outbox relay:", err)
+		}
+		if relayed == r.BatchSize && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Log.Println("Outbox relay stopped")
+			return
+		case <-time.After(r.PollInterval):
+		}
+	}
+}
+
+// relayBatch locks a batch of due records so several relays can run side by side, publishes them and
+// records the outcome in the same transaction
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var records []outbox.Record
+	query := `SELECT id, aggregate_type, aggregate_id, event_type, dedup_id, payload, attempts, created_at FROM outbox
+		WHERE published_at IS NULL AND available_at <= NOW() ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`
+	if err := tx.SelectContext(ctx, &records, query, r.BatchSize); err != nil {
+		return 0, fmt.Errorf("failed to load outbox records: %w", err)
+	}
+
+	for _, record := range records {
+		if err := r.publish(ctx, record); err != nil {
+			logger.Log.Errorln(fmt.Sprintf("failed to publish outbox record %d: %v", record.Id, err))
+			if err := markFailed(ctx, tx, record, err); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE outbox SET published_at = NOW(), attempts = attempts + 1 WHERE id = $1`, record.Id); err != nil {
+			return 0, fmt.Errorf("failed to mark outbox record %d published: %w", record.Id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+
+func (r *Relay) publish(ctx context.Context, record outbox.Record) error {
+	for _, publisher := range r.publishers {
+		if err := publisher.Publish(ctx, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prune deletes published records past the retention window, relays running side by side skip each other's rows
+func (r *Relay) prune(ctx context.Context) (int, error) {
+	pruned := 0
+	query := `DELETE FROM outbox WHERE id IN (SELECT id FROM outbox
+		WHERE published_at < NOW() - $1 * INTERVAL '1 second' LIMIT $2 FOR UPDATE SKIP LOCKED)`
+	for {
+		result, err := r.db.ExecContext(ctx, query, r.Retention.Seconds(), pruneBatchSize)
+		if err != nil {
+			return pruned, fmt.Errorf("failed to prune outbox records: %w", err)
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return pruned, err
+		}
+		pruned += int(count)
+		if count < pruneBatchSize {
+			return pruned, nil
+		}
+	}
+}
+
+// markFailed schedules the record again with exponential backoff
+func markFailed(ctx context.Context, tx *sqlx.Tx, record outbox.Record, cause error) error {
+	message := truncateError(cause.Error())
+
+	query := `UPDATE outbox SET attempts = attempts + 1, last_error = $2, available_at = NOW() + $3 * INTERVAL '1 millisecond'
+		WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, record.Id, message, backoff(record.Attempts).Milliseconds()); err != nil {
+		return fmt.Errorf("failed to reschedule outbox record %d: %w", record.Id, err)
+	}
+	return nil
+}
+
+// truncateError keeps last_error valid UTF-8 and at most maxErrorLen bytes, an invalid value would fail the
+// update and roll back the marks of the whole batch
+func truncateError(message string) string {
+	message = strings.ReplaceAll(strings.ToValidUTF8(message, "\uFFFD"), "\x00", "")
+	if len(message) <= maxErrorLen {
+		return message
+	}
+	end := maxErrorLen
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end]
+}
+
+// backoff doubles from one second for every failed attempt up to maxBackoff
+func backoff(attempts int) time.Duration {
+	if attempts > 16 {
+		return maxBackoff
+	}
+	return min(time.Second<<attempts, maxBackoff)
+}
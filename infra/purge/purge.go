@@ -0,0 +1,147 @@
+// Package purge permanently removes soft deleted deployments, projects and users once their retention window expired
+package purge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swarajkumarsingh/turbo-deploy/constants"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/artifacts"
+)
+
+// lockKey keeps replicas from purging the same rows at once
+const lockKey int64 = 7_425_218_360_200
+
+// Result counts the rows removed by one purge run
+type Result struct {
+	Deployments int
+	Projects    int
+	Users       int
+}
+
+type Purger struct {
+	db        *sqlx.DB
+	Retention time.Duration
+	Interval  time.Duration
+	BatchSize int
+	// DeleteArtifacts removes the build outputs of a deployment, the row is kept for the next run when it fails
+	DeleteArtifacts func(ctx context.Context, deploymentId int) error
+}
+
+func New(db *sqlx.DB) *Purger {
+	return &Purger{
+		db:        db,
+		Retention: constants.TrashRetention,
+		Interval:  constants.TrashPurgeInterval,
+		BatchSize: constants.TrashPurgeBatchSize,
+		DeleteArtifacts: func(ctx context.Context, deploymentId int) error {
+			_, err := artifacts.DeleteDeploymentOutputs(ctx, deploymentId)
+			return err
+		},
+	}
+}
+
+// Run purges once at start and then every Interval until ctx is cancelled
+func (p *Purger) Run(ctx context.Context) {
+	logger.Log.Println("Trash purge started, retention:", p.Retention)
+	for {
+		result, err := p.Purge(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Log.Errorln("trash purge:", err)
+		}
+		if result.Deployments+result.Projects+result.Users > 0 {
+			logger.Log.Println(fmt.Sprintf("Purged %d deployments, %d projects and %d users from the trash", result.Deployments, result.Projects, result.Users))
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Log.Println("Trash purge stopped")
+			return
+		case <-time.After(p.Interval):
+		}
+	}
+}
+
+// Purge removes expired deployments first, their build outputs before their rows, then the projects and users
+// left without deployments. A run is skipped while another replica holds the purge lock.
+func (p *Purger) Purge(ctx context.Context) (Result, error) {
+	var result Result
+
+	conn, err := p.db.Connx(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.GetContext(ctx, &locked, "SELECT pg_try_advisory_lock($1)", lockKey); err != nil {
+		return result, fmt.Errorf("failed to acquire purge lock: %w", err)
+	}
+	if !locked {
+		return result, nil
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	retention := p.Retention.Seconds()
+	result.Deployments, err = p.purgeDeployments(ctx, conn, retention)
+	if err != nil {
+		return result, err
+	}
+
+	query := `DELETE FROM projects p WHERE p.deleted_at < NOW() - $1 * INTERVAL '1 second'
+		AND NOT EXISTS (SELECT 1 FROM deployments d WHERE d.project_id = p.id)`
+	if result.Projects, err = execCount(ctx, conn, query, retention); err != nil {
+		return result, fmt.Errorf("failed to purge projects: %w", err)
+	}
+
+	query = `DELETE FROM users u WHERE u.deleted_at < NOW() - $1 * INTERVAL '1 second'
+		AND NOT EXISTS (SELECT 1 FROM projects p WHERE p.user_id = u.id)
+		AND NOT EXISTS (SELECT 1 FROM deployments d WHERE d.user_id = u.id)`
+	if result.Users, err = execCount(ctx, conn, query, retention); err != nil {
+		return result, fmt.Errorf("failed to purge users: %w", err)
+	}
+
+	return result, nil
+}
+
+// purgeDeployments walks the expired deployments in id order, deployments whose outputs could not be deleted
+// are skipped and retried on the next run
+func (p *Purger) purgeDeployments(ctx context.Context, conn *sqlx.Conn, retention float64) (int, error) {
+	purged, afterId := 0, 0
+	for {
+		var ids []int
+		query := `SELECT id FROM deployments WHERE deleted_at < NOW() - $1 * INTERVAL '1 second' AND id > $2 ORDER BY id LIMIT $3`
+		if err := conn.SelectContext(ctx, &ids, query, retention, afterId, p.BatchSize); err != nil {
+			return purged, fmt.Errorf("failed to load expired deployments: %w", err)
+		}
+
+		for _, id := range ids {
+			afterId = id
+			if err := p.DeleteArtifacts(ctx, id); err != nil {
+				logger.Log.Errorln("trash purge: failed to delete outputs of deployment", id, err)
+				continue
+			}
+			// logs and status events go with the row through ON DELETE CASCADE
+			if _, err := conn.ExecContext(ctx, "DELETE FROM deployments WHERE id = $1", id); err != nil {
+				return purged, fmt.Errorf("failed to purge deployment %d: %w", id, err)
+			}
+			purged++
+		}
+
+		if len(ids) < p.BatchSize {
+			return purged, nil
+		}
+	}
+}
+
+func execCount(ctx context.Context, conn *sqlx.Conn, query string, args ...interface{}) (int, error) {
+	result, err := conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	return int(count), err
+}
@@ -158,3 +158,8 @@ func Delete(key string) error {
 	}
 	return nil
 }
+
+// Publish sends message to the subscribers of channel
+func Publish(channel string, message string) error {
+	return rdb.Publish(ctx, channel, message).Err()
+}
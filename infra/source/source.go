@@ -0,0 +1,137 @@
+// Package source reads repository contents from the hosting provider of a project
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"time"
+)
+
+const (
+	GitHub = "github"
+
+	defaultTimeout  = 10 * time.Second
+	maxFileSize     = 1024 * 1024
+	githubApiUrl    = "https://api.github.com"
+	githubApiHeader = "2022-11-28"
+)
+
+var (
+	ErrUnsupportedSource = errors.New("unsupported source code provider")
+	ErrInvalidRepository = errors.New("invalid repository url")
+	ErrNotFound          = errors.New("repository or path not found")
+	ErrFileTooLarge      = errors.New("file is too large")
+)
+
+// Provider reads the default branch of a single repository, paths are relative to its root
+type Provider interface {
+	// ListDir returns the names of the entries of dir
+	ListDir(ctx context.Context, dir string) ([]string, error)
+	// ReadFile returns the contents of a file of at most 1MB
+	ReadFile(ctx context.Context, file string) ([]byte, error)
+}
+
+var githubRepoRegex = regexp.MustCompile(`^https://github\.com/([a-zA-Z0-9-]+)/([a-zA-Z0-9._-]+?)(\.git)?/?$`)
+
+// New returns the provider for a project's source_code and source_code_url, token is optional
+// and raises the GitHub rate limit
+func New(sourceCode, repositoryUrl, token string) (Provider, error) {
+	if sourceCode != GitHub {
+		return nil, ErrUnsupportedSource
+	}
+	match := githubRepoRegex.FindStringSubmatch(repositoryUrl)
+	if match == nil {
+		return nil, ErrInvalidRepository
+	}
+	return &githubProvider{
+		owner:  match[1],
+		repo:   match[2],
+		token:  token,
+		client: &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+type githubProvider struct {
+	owner  string
+	repo   string
+	token  string
+	client *http.Client
+}
+
+func (g *githubProvider) ListDir(ctx context.Context, dir string) ([]string, error) {
+	resp, err := g.get(ctx, dir, "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var entries []struct {
+		Name string `json:"name"`
+	}
+	// a file path returns a single object instead of a list
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, ErrNotFound
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	return names, nil
+}
+
+func (g *githubProvider) ReadFile(ctx context.Context, file string) ([]byte, error) {
+	resp, err := g.get(ctx, file, "application/vnd.github.raw+json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxFileSize {
+		return nil, ErrFileTooLarge
+	}
+	return content, nil
+}
+
+// get requests the contents api for p, the caller closes the body
+func (g *githubProvider) get(ctx context.Context, p string, accept string) (*http.Response, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/contents/%s", githubApiUrl, g.owner, g.repo,
+		(&url.URL{Path: path.Clean("/" + p)[1:]}).EscapedPath())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", githubApiHeader)
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("github contents api returned %d for %s", resp.StatusCode, p)
+	}
+}
@@ -0,0 +1,138 @@
+package consumer
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+const (
+	defaultBatchSize   = 100
+	defaultBatchWindow = time.Second
+	maxBatchEntries    = 10
+)
+
+// BatchHandler processes messages together, it returns one error per message in the same order,
+// a nil error acknowledges the message and anything else leaves it to be retried or quarantined
+type BatchHandler interface {
+	HandleBatch(ctx context.Context, msgs []*sqs.Message) []error
+}
+
+// BatchHandlerFunc adapts a function to the BatchHandler interface
+type BatchHandlerFunc func(ctx context.Context, msgs []*sqs.Message) []error
+
+func (f BatchHandlerFunc) HandleBatch(ctx context.Context, msgs []*sqs.Message) []error {
+	return f(ctx, msgs)
+}
+
+// NewBatch creates a consumer that collects messages into batches flushed by size or time,
+// every worker builds and handles its own batch
+func NewBatch(client Client, config Config, handler BatchHandler) *Consumer {
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.BatchWindow <= 0 {
+		config.BatchWindow = defaultBatchWindow
+	}
+
+	c := New(client, config, nil)
+	c.batchHandler = handler
+	return c
+}
+
+// collect builds batches from jobs until the channel is closed, flushing the remaining messages at the end
+func (c *Consumer) collect(ctx context.Context, jobs <-chan *sqs.Message) {
+	batch := make([]*sqs.Message, 0, c.config.BatchSize)
+	timer := time.NewTimer(c.config.BatchWindow)
+	defer timer.Stop()
+
+	flush := func() {
+		if len(batch) > 0 {
+			c.processBatch(ctx, batch)
+			batch = make([]*sqs.Message, 0, c.config.BatchSize)
+		}
+		timer.Reset(c.config.BatchWindow)
+	}
+
+	for {
+		select {
+		case msg, ok := <-jobs:
+			if !ok {
+				if len(batch) > 0 {
+					c.processBatch(ctx, batch)
+				}
+				return
+			}
+			batch = append(batch, msg)
+			if len(batch) >= c.config.BatchSize {
+				if !timer.Stop() {
+					<-timer.C
+				}
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		}
+	}
+}
+
+func (c *Consumer) processBatch(ctx context.Context, msgs []*sqs.Message) {
+	done := make(chan struct{})
+	go c.extendBatchVisibility(msgs, done)
+
+	errs := c.batchHandler.HandleBatch(ctx, msgs)
+	close(done)
+
+	for i, msg := range msgs {
+		var err error
+		if i < len(errs) {
+			err = errs[i]
+		}
+		c.complete(ctx, msg, err)
+	}
+}
+
+// extendBatchVisibility keeps all messages of a batch hidden from other consumers until done is closed
+func (c *Consumer) extendBatchVisibility(msgs []*sqs.Message, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(c.config.VisibilityTimeout) * time.Second / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			for start := 0; start < len(msgs); start += maxBatchEntries {
+				end := min(start+maxBatchEntries, len(msgs))
+				c.changeVisibilityBatch(msgs[start:end])
+			}
+		}
+	}
+}
+
+func (c *Consumer) changeVisibilityBatch(msgs []*sqs.Message) {
+	entries := make([]*sqs.ChangeMessageVisibilityBatchRequestEntry, 0, len(msgs))
+	for i, msg := range msgs {
+		entries = append(entries, &sqs.ChangeMessageVisibilityBatchRequestEntry{
+			Id:                aws.String(strconv.Itoa(i)),
+			ReceiptHandle:     msg.ReceiptHandle,
+			VisibilityTimeout: aws.Int64(c.config.VisibilityTimeout),
+		})
+	}
+
+	result, err := c.client.ChangeMessageVisibilityBatch(&sqs.ChangeMessageVisibilityBatchInput{
+		QueueUrl: aws.String(c.config.QueueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		log.Printf("Error extending visibility of %d messages: %v", len(msgs), err)
+		return
+	}
+	for _, failed := range result.Failed {
+		log.Printf("Error extending visibility: %s %s", aws.StringValue(failed.Code), aws.StringValue(failed.Message))
+	}
+}
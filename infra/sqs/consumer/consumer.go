@@ -0,0 +1,296 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+const (
+	defaultWorkers           = 10
+	defaultMaxMessages       = 10
+	defaultWaitTimeSeconds   = 20
+	defaultVisibilityTimeout = 30
+	defaultMaxReceives       = 5
+	deleteBatchSize          = 10
+	deleteFlushInterval      = time.Second
+	receiveErrorBackoff      = 5 * time.Second
+)
+
+// Handler processes a single message, returning an error leaves the message on the queue to be received again
+// until it is quarantined
+type Handler interface {
+	Handle(ctx context.Context, msg *sqs.Message) error
+}
+
+// HandlerFunc adapts a function to the Handler interface
+type HandlerFunc func(ctx context.Context, msg *sqs.Message) error
+
+func (f HandlerFunc) Handle(ctx context.Context, msg *sqs.Message) error {
+	return f(ctx, msg)
+}
+
+// Config tunes how messages are received and processed, zero values fall back to the defaults
+type Config struct {
+	QueueURL string
+	// Workers is the number of messages handled concurrently
+	Workers int
+	// MaxMessages is the number of messages requested per receive call, at most 10
+	MaxMessages int64
+	// WaitTimeSeconds enables long polling, at most 20
+	WaitTimeSeconds int64
+	// VisibilityTimeout is extended while a handler is still running
+	VisibilityTimeout int64
+	// MaxReceives is the number of attempts after which a failing message is quarantined
+	MaxReceives int
+	// DeadLetterQueueURL receives quarantined messages when set
+	DeadLetterQueueURL string
+	// Quarantine stores quarantined messages when set, failing messages stay on the queue
+	// when neither a dead-letter queue nor a quarantine is configured
+	Quarantine Quarantine
+	// BatchSize and BatchWindow bound the batches passed to a BatchHandler
+	BatchSize   int
+	BatchWindow time.Duration
+}
+
+// Client is the part of the SQS api used by consumers, implemented by *sqs.SQS and the in-memory queue
+type Client interface {
+	ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error)
+	SendMessage(input *sqs.SendMessageInput) (*sqs.SendMessageOutput, error)
+	DeleteMessageBatch(input *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error)
+	ChangeMessageVisibility(input *sqs.ChangeMessageVisibilityInput) (*sqs.ChangeMessageVisibilityOutput, error)
+	ChangeMessageVisibilityBatch(input *sqs.ChangeMessageVisibilityBatchInput) (*sqs.ChangeMessageVisibilityBatchOutput, error)
+}
+
+// Consumer receives messages from a queue and dispatches them to a handler on a pool of workers
+type Consumer struct {
+	client       Client
+	config       Config
+	handler      Handler
+	batchHandler BatchHandler
+	deletes      chan *sqs.Message
+}
+
+func New(client Client, config Config, handler Handler) *Consumer {
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers
+	}
+	if config.MaxMessages <= 0 || config.MaxMessages > 10 {
+		config.MaxMessages = defaultMaxMessages
+	}
+	if config.WaitTimeSeconds <= 0 || config.WaitTimeSeconds > 20 {
+		config.WaitTimeSeconds = defaultWaitTimeSeconds
+	}
+	if config.VisibilityTimeout <= 0 {
+		config.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if config.MaxReceives <= 0 {
+		config.MaxReceives = defaultMaxReceives
+	}
+
+	return &Consumer{
+		client:  client,
+		config:  config,
+		handler: handler,
+		deletes: make(chan *sqs.Message, deleteBatchSize*config.Workers),
+	}
+}
+
+// Run consumes messages until ctx is cancelled, then waits for in-flight messages to finish and be deleted
+func (c *Consumer) Run(ctx context.Context) error {
+	if c.config.QueueURL == "" {
+		return errors.New("queue url is required")
+	}
+
+	jobs := make(chan *sqs.Message)
+
+	var workers sync.WaitGroup
+	for i := 0; i < c.config.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			if c.batchHandler != nil {
+				c.collect(context.WithoutCancel(ctx), jobs)
+				return
+			}
+			for msg := range jobs {
+				c.process(context.WithoutCancel(ctx), msg)
+			}
+		}()
+	}
+
+	deleterDone := make(chan struct{})
+	go func() {
+		defer close(deleterDone)
+		c.runDeleter()
+	}()
+
+	log.Printf("Listening to messages on %s with %d workers", c.config.QueueURL, c.config.Workers)
+	c.poll(ctx, jobs)
+
+	log.Println("Shutting down, waiting for in-flight messages")
+	close(jobs)
+	workers.Wait()
+	close(c.deletes)
+	<-deleterDone
+
+	log.Println("Consumer stopped")
+	return nil
+}
+
+// WithShutdownSignals returns a context cancelled on SIGINT or SIGTERM
+func WithShutdownSignals(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+}
+
+// ReceiveCount returns how many times the message has been received, including the current delivery
+func ReceiveCount(msg *sqs.Message) int {
+	count, err := strconv.Atoi(aws.StringValue(msg.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]))
+	if err != nil {
+		return 1
+	}
+	return count
+}
+
+func (c *Consumer) poll(ctx context.Context, jobs chan<- *sqs.Message) {
+	for ctx.Err() == nil {
+		result, err := c.client.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(c.config.QueueURL),
+			MaxNumberOfMessages: aws.Int64(c.config.MaxMessages),
+			WaitTimeSeconds:     aws.Int64(c.config.WaitTimeSeconds),
+			VisibilityTimeout:   aws.Int64(c.config.VisibilityTimeout),
+			AttributeNames: []*string{
+				aws.String(sqs.MessageSystemAttributeNameApproximateReceiveCount),
+				aws.String(sqs.MessageSystemAttributeNameMessageGroupId),
+			},
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Error receiving message: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(receiveErrorBackoff):
+			}
+			continue
+		}
+
+		// messages already received are still handed to workers so they are not left invisible during shutdown
+		for _, msg := range result.Messages {
+			jobs <- msg
+		}
+	}
+}
+
+func (c *Consumer) process(ctx context.Context, msg *sqs.Message) {
+	done := make(chan struct{})
+	go c.extendVisibility(msg, done)
+
+	err := c.handler.Handle(ctx, msg)
+	close(done)
+
+	c.complete(ctx, msg, err)
+}
+
+// complete acknowledges a handled message, or quarantines it once it has failed too often
+func (c *Consumer) complete(ctx context.Context, msg *sqs.Message, err error) {
+	if err != nil {
+		log.Printf("Error processing message %s: %v", aws.StringValue(msg.MessageId), err)
+		if c.shouldQuarantine(msg, err) {
+			c.quarantine(ctx, msg, err)
+		}
+		return
+	}
+	c.deletes <- msg
+}
+
+// extendVisibility keeps the message hidden from other consumers until done is closed
+func (c *Consumer) extendVisibility(msg *sqs.Message, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(c.config.VisibilityTimeout) * time.Second / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			_, err := c.client.ChangeMessageVisibility(&sqs.ChangeMessageVisibilityInput{
+				QueueUrl:          aws.String(c.config.QueueURL),
+				ReceiptHandle:     msg.ReceiptHandle,
+				VisibilityTimeout: aws.Int64(c.config.VisibilityTimeout),
+			})
+			if err != nil {
+				log.Printf("Error extending visibility of message %s: %v", aws.StringValue(msg.MessageId), err)
+			}
+		}
+	}
+}
+
+// runDeleter acknowledges handled messages in batches until the deletes channel is closed
+func (c *Consumer) runDeleter() {
+	ticker := time.NewTicker(deleteFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*sqs.Message, 0, deleteBatchSize)
+	for {
+		select {
+		case msg, ok := <-c.deletes:
+			if !ok {
+				c.deleteBatch(batch)
+				return
+			}
+			batch = append(batch, msg)
+			if len(batch) == deleteBatchSize {
+				c.deleteBatch(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			c.deleteBatch(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (c *Consumer) deleteBatch(batch []*sqs.Message) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := DeleteMessages(c.client, c.config.QueueURL, batch); err != nil {
+		log.Printf("Error deleting messages: %v", err)
+	}
+}
+
+// DeleteMessages deletes up to 10 messages in a single request, failed entries are logged and left on the queue
+func DeleteMessages(client Client, queueURL string, msgs []*sqs.Message) error {
+	entries := make([]*sqs.DeleteMessageBatchRequestEntry, 0, len(msgs))
+	for i, msg := range msgs {
+		entries = append(entries, &sqs.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: msg.ReceiptHandle,
+		})
+	}
+
+	result, err := client.DeleteMessageBatch(&sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(queueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, failed := range result.Failed {
+		log.Printf("Error deleting message: %s %s", aws.StringValue(failed.Code), aws.StringValue(failed.Message))
+	}
+	return nil
+}
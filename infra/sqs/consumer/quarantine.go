@@ -0,0 +1,123 @@
+package consumer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+const quarantineGroupId = "quarantine"
+
+// Quarantine stores messages that could not be processed so they can be inspected and redriven later
+type Quarantine interface {
+	Quarantine(ctx context.Context, queueURL string, msg *sqs.Message, cause error) error
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks a handler error that will never succeed on retry, such as a malformed payload,
+// so the message is quarantined right away instead of after MaxReceives attempts
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// shouldQuarantine reports whether a failed message has used up its attempts
+func (c *Consumer) shouldQuarantine(msg *sqs.Message, err error) bool {
+	if c.config.DeadLetterQueueURL == "" && c.config.Quarantine == nil {
+		return false
+	}
+	return IsPermanent(err) || ReceiveCount(msg) >= c.config.MaxReceives
+}
+
+// quarantine moves the message to the quarantine store and the dead-letter queue, it is only deleted from the
+// source queue once both succeeded so nothing is lost when either of them is unavailable. The store comes first
+// and ignores a message it already holds, so a retry never stores it twice. The dead-letter queue is
+// at-least-once: when the delete fails after a successful send the retry sends the message again, FIFO
+// dead-letter queues drop that copy within their five minute deduplication window as the message id is the
+// deduplication id.
+func (c *Consumer) quarantine(ctx context.Context, msg *sqs.Message, cause error) {
+	log.Printf("Quarantining message %s after %d attempts: %v", aws.StringValue(msg.MessageId), ReceiveCount(msg), cause)
+
+	if c.config.Quarantine != nil {
+		if err := c.config.Quarantine.Quarantine(ctx, c.config.QueueURL, msg, cause); err != nil {
+			log.Printf("Error storing quarantined message %s: %v", aws.StringValue(msg.MessageId), err)
+			return
+		}
+	}
+
+	if c.config.DeadLetterQueueURL != "" {
+		if err := c.sendToDeadLetterQueue(msg); err != nil {
+			log.Printf("Error moving message %s to the dead-letter queue: %v", aws.StringValue(msg.MessageId), err)
+			return
+		}
+	}
+
+	c.deletes <- msg
+}
+
+func (c *Consumer) sendToDeadLetterQueue(msg *sqs.Message) error {
+	input := &sqs.SendMessageInput{
+		QueueUrl:    aws.String(c.config.DeadLetterQueueURL),
+		MessageBody: msg.Body,
+	}
+	if strings.HasSuffix(c.config.DeadLetterQueueURL, ".fifo") {
+		groupId := aws.StringValue(msg.Attributes[sqs.MessageSystemAttributeNameMessageGroupId])
+		if groupId == "" {
+			groupId = quarantineGroupId
+		}
+		input.MessageGroupId = aws.String(groupId)
+		input.MessageDeduplicationId = msg.MessageId
+	}
+
+	_, err := c.client.SendMessage(input)
+	return err
+}
+
+// execer is satisfied by both *sql.DB and *sqlx.DB
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// PostgresQuarantine stores quarantined messages in the quarantined_messages table, once per message id
+type PostgresQuarantine struct {
+	db execer
+}
+
+func NewPostgresQuarantine(db execer) *PostgresQuarantine {
+	return &PostgresQuarantine{db: db}
+}
+
+func (q *PostgresQuarantine) Quarantine(ctx context.Context, queueURL string, msg *sqs.Message, cause error) error {
+	query := `INSERT INTO quarantined_messages(source_queue, message_id, message_group_id, body, error, receive_count)
+		VALUES($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (source_queue, message_id) DO NOTHING`
+
+	_, err := q.db.ExecContext(ctx, query, queueURL, aws.StringValue(msg.MessageId),
+		aws.StringValue(msg.Attributes[sqs.MessageSystemAttributeNameMessageGroupId]),
+		aws.StringValue(msg.Body), cause.Error(), ReceiveCount(msg))
+	return err
+}
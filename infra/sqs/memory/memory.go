@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+const (
+	defaultVisibilityTimeout = 30 * time.Second
+	pollInterval             = 50 * time.Millisecond
+	errCodeInvalidReceipt    = "ReceiptHandleIsInvalid"
+)
+
+type message struct {
+	id            string
+	body          string
+	groupId       string
+	receiptHandle string
+	receiveCount  int
+	visibleAt     time.Time
+}
+
+// Queue is an in-memory stand-in for SQS that implements the calls made by the producers and consumers.
+// Every queue url is a separate queue, created on first use. It keeps visibility timeouts and receive counts
+// but does not enforce FIFO ordering or deduplication.
+type Queue struct {
+	mu       sync.Mutex
+	queues   map[string][]*message
+	sequence int
+}
+
+func New() *Queue {
+	return &Queue{queues: make(map[string][]*message)}
+}
+
+// Len returns the number of messages on the queue, including those currently in flight
+func (q *Queue) Len(queueURL string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queues[queueURL])
+}
+
+func (q *Queue) SendMessage(input *sqs.SendMessageInput) (*sqs.SendMessageOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.sequence++
+	msg := &message{
+		id:        fmt.Sprintf("msg-%d", q.sequence),
+		body:      aws.StringValue(input.MessageBody),
+		groupId:   aws.StringValue(input.MessageGroupId),
+		visibleAt: time.Now().Add(time.Duration(aws.Int64Value(input.DelaySeconds)) * time.Second),
+	}
+	queueURL := aws.StringValue(input.QueueUrl)
+	q.queues[queueURL] = append(q.queues[queueURL], msg)
+
+	return &sqs.SendMessageOutput{MessageId: aws.String(msg.id)}, nil
+}
+
+// ReceiveMessageWithContext returns visible messages, waiting up to WaitTimeSeconds for one to arrive
+func (q *Queue) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	deadline := time.Now().Add(time.Duration(aws.Int64Value(input.WaitTimeSeconds)) * time.Second)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if messages := q.receive(input); len(messages) > 0 || !time.Now().Before(deadline) {
+			return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) receive(input *sqs.ReceiveMessageInput) []*sqs.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	maxMessages := int(aws.Int64Value(input.MaxNumberOfMessages))
+	if maxMessages <= 0 {
+		maxMessages = 1
+	}
+	visibilityTimeout := defaultVisibilityTimeout
+	if input.VisibilityTimeout != nil {
+		visibilityTimeout = time.Duration(*input.VisibilityTimeout) * time.Second
+	}
+
+	now := time.Now()
+	messages := make([]*sqs.Message, 0, maxMessages)
+	for _, msg := range q.queues[aws.StringValue(input.QueueUrl)] {
+		if len(messages) == maxMessages {
+			break
+		}
+		if msg.visibleAt.After(now) {
+			continue
+		}
+
+		q.sequence++
+		msg.receiveCount++
+		msg.receiptHandle = fmt.Sprintf("%s-%d", msg.id, q.sequence)
+		msg.visibleAt = now.Add(visibilityTimeout)
+
+		attributes := map[string]*string{
+			sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String(strconv.Itoa(msg.receiveCount)),
+		}
+		if msg.groupId != "" {
+			attributes[sqs.MessageSystemAttributeNameMessageGroupId] = aws.String(msg.groupId)
+		}
+
+		messages = append(messages, &sqs.Message{
+			MessageId:     aws.String(msg.id),
+			ReceiptHandle: aws.String(msg.receiptHandle),
+			Body:          aws.String(msg.body),
+			Attributes:    attributes,
+		})
+	}
+	return messages
+}
+
+func (q *Queue) DeleteMessage(input *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.delete(aws.StringValue(input.QueueUrl), aws.StringValue(input.ReceiptHandle)) {
+		return nil, fmt.Errorf("%s: %s", errCodeInvalidReceipt, aws.StringValue(input.ReceiptHandle))
+	}
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (q *Queue) DeleteMessageBatch(input *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	output := &sqs.DeleteMessageBatchOutput{}
+	for _, entry := range input.Entries {
+		if q.delete(aws.StringValue(input.QueueUrl), aws.StringValue(entry.ReceiptHandle)) {
+			output.Successful = append(output.Successful, &sqs.DeleteMessageBatchResultEntry{Id: entry.Id})
+			continue
+		}
+		output.Failed = append(output.Failed, &sqs.BatchResultErrorEntry{
+			Id:          entry.Id,
+			Code:        aws.String(errCodeInvalidReceipt),
+			SenderFault: aws.Bool(true),
+		})
+	}
+	return output, nil
+}
+
+func (q *Queue) ChangeMessageVisibility(input *sqs.ChangeMessageVisibilityInput) (*sqs.ChangeMessageVisibilityOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.changeVisibility(aws.StringValue(input.QueueUrl), aws.StringValue(input.ReceiptHandle), aws.Int64Value(input.VisibilityTimeout)) {
+		return nil, fmt.Errorf("%s: %s", errCodeInvalidReceipt, aws.StringValue(input.ReceiptHandle))
+	}
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func (q *Queue) ChangeMessageVisibilityBatch(input *sqs.ChangeMessageVisibilityBatchInput) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	output := &sqs.ChangeMessageVisibilityBatchOutput{}
+	for _, entry := range input.Entries {
+		if q.changeVisibility(aws.StringValue(input.QueueUrl), aws.StringValue(entry.ReceiptHandle), aws.Int64Value(entry.VisibilityTimeout)) {
+			output.Successful = append(output.Successful, &sqs.ChangeMessageVisibilityBatchResultEntry{Id: entry.Id})
+			continue
+		}
+		output.Failed = append(output.Failed, &sqs.BatchResultErrorEntry{
+			Id:          entry.Id,
+			Code:        aws.String(errCodeInvalidReceipt),
+			SenderFault: aws.Bool(true),
+		})
+	}
+	return output, nil
+}
+
+// delete removes the message currently received with receiptHandle, the caller holds the lock
+func (q *Queue) delete(queueURL, receiptHandle string) bool {
+	messages := q.queues[queueURL]
+	for i, msg := range messages {
+		if msg.receiptHandle != "" && msg.receiptHandle == receiptHandle {
+			q.queues[queueURL] = append(messages[:i], messages[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// changeVisibility updates the message currently received with receiptHandle, the caller holds the lock
+func (q *Queue) changeVisibility(queueURL, receiptHandle string, seconds int64) bool {
+	for _, msg := range q.queues[queueURL] {
+		if msg.receiptHandle != "" && msg.receiptHandle == receiptHandle {
+			msg.visibleAt = time.Now().Add(time.Duration(seconds) * time.Second)
+			return true
+		}
+	}
+	return false
+}
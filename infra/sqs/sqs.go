@@ -1,6 +1,7 @@
 package sqs
 
 import (
+	"sync"
 	"time"
 
 	"github.com/swarajkumarsingh/turbo-deploy/conf"
@@ -12,20 +13,45 @@ import (
 	"github.com/aws/aws-sdk-go/service/sqs"
 )
 
-var sess, _ = session.NewSessionWithOptions(session.Options{
-	Config: aws.Config{
-		Region:      aws.String(conf.AWS_REGION),
-		Credentials: credentials.NewStaticCredentials(conf.AWS_ACCESS_KEY, conf.AWS_SECRET_ACCESS_KEY, conf.AWS_TOKEN),
-	},
-})
 var MaxTry = 3
 
-var svc = sqs.New(sess)
+// Sender is the part of the SQS api used to publish messages, implemented by *sqs.SQS and the in-memory queue
+type Sender interface {
+	SendMessage(input *sqs.SendMessageInput) (*sqs.SendMessageOutput, error)
+}
+
+var (
+	svc     Sender
+	svcOnce sync.Once
+)
+
+// SetClient replaces the SQS client, used to run against the in-memory queue
+func SetClient(client Sender) {
+	svcOnce.Do(func() {})
+	svc = client
+}
+
+// client creates the SQS client on first use so importing the package does not require AWS configuration
+func client() Sender {
+	svcOnce.Do(func() {
+		config := aws.Config{
+			Region:      aws.String(conf.AWS_REGION),
+			Credentials: credentials.NewStaticCredentials(conf.AWS_ACCESS_KEY, conf.AWS_SECRET_ACCESS_KEY, conf.AWS_TOKEN),
+		}
+		if conf.AWS_SQS_ENDPOINT != "" {
+			config.Endpoint = aws.String(conf.AWS_SQS_ENDPOINT)
+		}
+
+		sess := session.Must(session.NewSessionWithOptions(session.Options{Config: config}))
+		svc = sqs.New(sess)
+	})
+	return svc
+}
 
 // SendFIFOMessage sends events to SQS queue
 func SendFIFOMessage(messageID string, messageBody string, messageGroupID string, queueURL string) error {
 	return retry.CustomRetry(MaxTry, 1*time.Second, func() error {
-		_, err := svc.SendMessage(&sqs.SendMessageInput{
+		_, err := client().SendMessage(&sqs.SendMessageInput{
 			DelaySeconds:           aws.Int64(0),
 			MessageBody:            &messageBody,
 			MessageGroupId:         &messageGroupID,
@@ -35,3 +61,14 @@ func SendFIFOMessage(messageID string, messageBody string, messageGroupID string
 		return err
 	})
 }
+
+// SendMessage sends events to a standard SQS queue
+func SendMessage(messageBody string, queueURL string) error {
+	return retry.CustomRetry(MaxTry, 1*time.Second, func() error {
+		_, err := client().SendMessage(&sqs.SendMessageInput{
+			MessageBody: &messageBody,
+			QueueUrl:    &queueURL,
+		})
+		return err
+	})
+}
@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const maxRedirects = 5
+
+var ErrForbiddenAddress = errors.New("webhook url resolves to a private or reserved address")
+
+// blockedNetworks are not covered by the net.IP classification helpers
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",      // this network
+	"100.64.0.0/10",  // carrier grade nat
+	"192.0.0.0/24",   // protocol assignments
+	"198.18.0.0/15",  // benchmarking
+	"240.0.0.0/4",    // reserved, includes broadcast
+	"64:ff9b::/96",   // nat64 can reach any ipv4 address
+	"64:ff9b:1::/48", // local use nat64
+	"2002::/16",      // 6to4 embeds an ipv4 address
+	"fec0::/10",      // deprecated site local
+)
+
+// IsPublicIP reports whether ip may receive webhook deliveries, loopback, private, link local (which holds
+// the cloud metadata endpoint), multicast and unspecified addresses may not
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateURL checks a webhook url when it is saved, every address its host resolves to must be public.
+// Deliveries check the address they connect to again, so a host that later resolves elsewhere is still refused
+func ValidateURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("invalid webhook url %q", rawURL)
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", parsed.Hostname())
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", parsed.Hostname(), err)
+	}
+	for _, ip := range ips {
+		if !IsPublicIP(ip) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// NewClient returns the client deliveries are sent with. It only connects to public addresses, the check
+// runs on the resolved address of every connection so redirects and DNS rebinding are covered too
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// a proxy would be the address checked instead of the webhook host
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   timeout,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/retry"
+)
+
+const (
+	EventDeploymentStarted   = "deployment.started"
+	EventDeploymentSucceeded = "deployment.succeeded"
+	EventDeploymentFailed    = "deployment.failed"
+)
+
+const (
+	EventHeader     = "X-Turbo-Event"
+	DeliveryHeader  = "X-Turbo-Delivery"
+	SignatureHeader = "X-Turbo-Signature"
+)
+
+const (
+	defaultAttempts    = 3
+	defaultBackoff     = time.Second
+	defaultTimeout     = 10 * time.Second
+	maxResponseBodyLen = 4096
+)
+
+// Events are the lifecycle events a webhook can subscribe to
+var Events = []string{EventDeploymentStarted, EventDeploymentSucceeded, EventDeploymentFailed}
+
+// ValidEvent reports whether event is one of Events
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook is the part of a subscription needed to deliver to it
+type Webhook struct {
+	Id        int    `db:"id"`
+	ProjectId int    `db:"project_id"`
+	URL       string `db:"url"`
+	Secret    string `db:"secret"`
+}
+
+// Delivery is a single attempt to notify a webhook, including its retries
+type Delivery struct {
+	Id           int64  `json:"id" db:"id"`
+	WebhookId    int    `json:"webhook_id" db:"webhook_id"`
+	Event        string `json:"event" db:"event"`
+	Payload      string `json:"payload" db:"payload"`
+	ResponseCode int    `json:"response_code" db:"response_code"`
+	ResponseBody string `json:"response_body" db:"response_body"`
+	Error        string `json:"error" db:"error"`
+	Attempts     int    `json:"attempts" db:"attempts"`
+	DurationMs   int64  `json:"duration_ms" db:"duration_ms"`
+	Success      bool   `json:"success" db:"success"`
+	RedeliveryOf *int64 `json:"redelivery_of" db:"redelivery_of"`
+}
+
+type envelope struct {
+	Event     string      `json:"event"`
+	ProjectId int         `json:"project_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher signs and sends payloads to the webhooks of a project and records every delivery
+type Dispatcher struct {
+	db sqlx.ExtContext
+	// Client sends the requests, its timeout bounds a single attempt
+	Client *http.Client
+	// Attempts and Backoff are passed to retry.CustomRetry
+	Attempts int
+	Backoff  time.Duration
+}
+
+func NewDispatcher(db sqlx.ExtContext) *Dispatcher {
+	return &Dispatcher{
+		db:       db,
+		Client:   NewClient(defaultTimeout),
+		Attempts: defaultAttempts,
+		Backoff:  defaultBackoff,
+	}
+}
+
+// Sign returns the signature header for payload, receivers recompute the HMAC-SHA256 of "<timestamp>.<payload>"
+// with their secret and compare it to v1, the timestamp lets them reject replayed requests
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// ErrDeliveryFailed is wrapped by Dispatch errors of deliveries that were recorded as failed, they can be redelivered
+var ErrDeliveryFailed = errors.New("webhook delivery failed")
+
+// Dispatch delivers event to every active webhook of the project subscribed to it
+func (d *Dispatcher) Dispatch(ctx context.Context, projectId int, event string, data interface{}) error {
+	var hooks []Webhook
+	query := `SELECT id, project_id, url, secret FROM webhooks WHERE project_id = $1 AND active AND $2 = ANY(events)`
+	if err := sqlx.SelectContext(ctx, d.db, &hooks, query, projectId, event); err != nil {
+		return fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(envelope{Event: event, ProjectId: projectId, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	var errs []error
+	for _, hook := range hooks {
+		if _, err := d.Deliver(ctx, hook, event, payload, nil); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %d: %w: %w", hook.Id, ErrDeliveryFailed, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Redeliver sends the payload of a past delivery again, it is recorded as a new delivery
+func (d *Dispatcher) Redeliver(ctx context.Context, deliveryId int64) (Delivery, error) {
+	var row struct {
+		Webhook
+		Event   string `db:"event"`
+		Payload string `db:"payload"`
+	}
+	query := `SELECT w.id, w.project_id, w.url, w.secret, wd.event, wd.payload
+		FROM webhook_deliveries wd JOIN webhooks w ON w.id = wd.webhook_id WHERE wd.id = $1`
+	if err := sqlx.GetContext(ctx, d.db, &row, query, deliveryId); err != nil {
+		return Delivery{}, err
+	}
+
+	return d.Deliver(ctx, row.Webhook, row.Event, []byte(row.Payload), &deliveryId)
+}
+
+// Deliver sends payload to the webhook, retrying connection errors, timeouts and 5xx or 429 responses.
+// The delivery is recorded before the first attempt so its id can be sent along, an error is returned
+// when every attempt failed.
+func (d *Dispatcher) Deliver(ctx context.Context, hook Webhook, event string, payload []byte, redeliveryOf *int64) (Delivery, error) {
+	delivery := Delivery{WebhookId: hook.Id, Event: event, Payload: string(payload), RedeliveryOf: redeliveryOf}
+
+	query := `INSERT INTO webhook_deliveries(webhook_id, event, payload, redelivery_of) VALUES($1, $2, $3, $4) RETURNING id`
+	if err := d.db.QueryRowxContext(ctx, query, hook.Id, event, delivery.Payload, redeliveryOf).Scan(&delivery.Id); err != nil {
+		return delivery, fmt.Errorf("failed to record delivery: %w", err)
+	}
+
+	// retry.CustomRetry needs a positive backoff to compute its jitter
+	backoff := d.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+
+	start := time.Now()
+	err := retry.CustomRetry(d.Attempts, backoff, func() error {
+		delivery.Attempts++
+		return d.send(ctx, hook, &delivery, payload)
+	})
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	delivery.Success = err == nil
+	delivery.Error = ""
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	query = `UPDATE webhook_deliveries SET response_code = $2, response_body = $3, error = $4, attempts = $5,
+		duration_ms = $6, success = $7, delivered_at = NOW() WHERE id = $1`
+	if _, dbErr := d.db.ExecContext(ctx, query, delivery.Id, delivery.ResponseCode, delivery.ResponseBody,
+		delivery.Error, delivery.Attempts, delivery.DurationMs, delivery.Success); dbErr != nil {
+		return delivery, fmt.Errorf("failed to record delivery result: %w", dbErr)
+	}
+	return delivery, err
+}
+
+// send makes a single attempt, responses that will not change on retry stop the retries
+func (d *Dispatcher) send(ctx context.Context, hook Webhook, delivery *Delivery, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return retry.NewStop(err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Turbo-Deploy-Webhook")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, time.Now().Unix(), payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		delivery.ResponseCode = 0
+		delivery.ResponseBody = ""
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodyLen))
+	delivery.ResponseCode = resp.StatusCode
+	// the body is stored as text, postgres rejects invalid utf-8 and NUL bytes
+	delivery.ResponseBody = strings.ReplaceAll(strings.ToValidUTF8(string(body), ""), "\x00", "")
+
+	switch s := resp.StatusCode; {
+	case s >= 200 && s < 300:
+		return nil
+	case s >= 500 || s == http.StatusTooManyRequests || s == http.StatusRequestTimeout:
+		return fmt.Errorf("server error: %d", s)
+	default:
+		return retry.NewStop(fmt.Sprintf("client error: %d", s))
+	}
+}
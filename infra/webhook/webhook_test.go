@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+const testSecret = "whsec_test"
+
+func newTestDispatcher(t *testing.T, client *http.Client) (*Dispatcher, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	d := NewDispatcher(sqlx.NewDb(conn, "postgres"))
+	d.Client = client
+	d.Backoff = time.Millisecond
+	return d, mock
+}
+
+func expectDelivery(mock sqlmock.Sqlmock, deliveryId int64, redeliveryOf interface{}, code, attempts int, success bool) {
+	mock.ExpectQuery(`INSERT INTO webhook_deliveries`).
+		WithArgs(1, EventDeploymentSucceeded, sqlmock.AnyArg(), redeliveryOf).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(deliveryId))
+	mock.ExpectExec(`UPDATE webhook_deliveries SET`).
+		WithArgs(deliveryId, code, sqlmock.AnyArg(), sqlmock.AnyArg(), attempts, sqlmock.AnyArg(), success).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"event":"deployment.succeeded"}`)
+	want := "t=1700000000,v1=aef3817529f205f3857cad525f83d83a39a7ff19de3ed2230ca4210732615799"
+	if got := Sign(testSecret, 1700000000, payload); got != want {
+		t.Fatalf("Sign() = %s, want %s", got, want)
+	}
+}
+
+func TestDeliverSignsRequest(t *testing.T) {
+	payload := []byte(`{"event":"deployment.succeeded"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(EventHeader) != EventDeploymentSucceeded || r.Header.Get(DeliveryHeader) != "7" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+
+		// t=<timestamp>,v1=<hex hmac of "<timestamp>.<payload>">
+		timestamp, signature, _ := strings.Cut(strings.TrimPrefix(r.Header.Get(SignatureHeader), "t="), ",v1=")
+		mac := hmac.New(sha256.New, []byte(testSecret))
+		mac.Write([]byte(timestamp + "." + string(payload)))
+		if !hmac.Equal([]byte(signature), []byte(hex.EncodeToString(mac.Sum(nil)))) {
+			t.Errorf("signature %q does not match the payload", signature)
+		}
+		if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+			t.Errorf("invalid timestamp %q", timestamp)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d, mock := newTestDispatcher(t, server.Client())
+	expectDelivery(mock, 7, nil, http.StatusNoContent, 1, true)
+
+	hook := Webhook{Id: 1, ProjectId: 2, URL: server.URL, Secret: testSecret}
+	if _, err := d.Deliver(context.Background(), hook, EventDeploymentSucceeded, payload, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  func(attempt int32, w http.ResponseWriter)
+		code     int
+		attempts int
+		success  bool
+	}{
+		{
+			name: "server errors are retried",
+			handler: func(attempt int32, w http.ResponseWriter) {
+				if attempt < 3 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			},
+			code: http.StatusOK, attempts: 3, success: true,
+		},
+		{
+			name: "timeouts are retried",
+			handler: func(attempt int32, w http.ResponseWriter) {
+				if attempt == 1 {
+					time.Sleep(200 * time.Millisecond)
+				}
+				w.WriteHeader(http.StatusOK)
+			},
+			code: http.StatusOK, attempts: 2, success: true,
+		},
+		{
+			name: "every attempt fails",
+			handler: func(_ int32, w http.ResponseWriter) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			code: http.StatusServiceUnavailable, attempts: 3, success: false,
+		},
+		{
+			name: "client errors are not retried",
+			handler: func(_ int32, w http.ResponseWriter) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			code: http.StatusNotFound, attempts: 1, success: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				test.handler(atomic.AddInt32(&requests, 1), w)
+			}))
+			defer server.Close()
+
+			client := server.Client()
+			client.Timeout = 100 * time.Millisecond
+			d, mock := newTestDispatcher(t, client)
+			expectDelivery(mock, 1, nil, test.code, test.attempts, test.success)
+
+			hook := Webhook{Id: 1, ProjectId: 2, URL: server.URL, Secret: testSecret}
+			delivery, err := d.Deliver(context.Background(), hook, EventDeploymentSucceeded, []byte(`{}`), nil)
+			if (err == nil) != test.success {
+				t.Fatalf("Deliver() error = %v, want success %v", err, test.success)
+			}
+			if int(atomic.LoadInt32(&requests)) != test.attempts || delivery.Attempts != test.attempts {
+				t.Fatalf("got %d requests and %d attempts, want %d", requests, delivery.Attempts, test.attempts)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRedeliverRecordsNewDelivery(t *testing.T) {
+	payload := `{"event":"deployment.succeeded","project_id":2}`
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(DeliveryHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	d, mock := newTestDispatcher(t, server.Client())
+	mock.ExpectQuery(`SELECT w.id, w.project_id, w.url, w.secret, wd.event, wd.payload`).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "url", "secret", "event", "payload"}).
+			AddRow(1, 2, server.URL, testSecret, EventDeploymentSucceeded, payload))
+	expectDelivery(mock, 6, int64(5), http.StatusOK, 1, true)
+
+	delivery, err := d.Redeliver(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Id != 6 || delivery.RedeliveryOf == nil || *delivery.RedeliveryOf != 5 || delivery.Payload != payload {
+		t.Fatalf("unexpected delivery %+v", delivery)
+	}
+	if received != "6" {
+		t.Fatalf("redelivery sent delivery id %q, want the new delivery 6", received)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":      true,
+		"2606:4700::1111":    true,
+		"127.0.0.1":          false,
+		"10.1.2.3":           false,
+		"172.16.0.1":         false,
+		"192.168.1.1":        false,
+		"169.254.169.254":    false,
+		"100.64.0.1":         false,
+		"0.0.0.0":            false,
+		"::1":                false,
+		"::":                 false,
+		"fd00::1":            false,
+		"fe80::1":            false,
+		"::ffff:127.0.0.1":   false,
+		"64:ff9b::a9fe:a9fe": false,
+	}
+	for address, want := range tests {
+		if got := IsPublicIP(net.ParseIP(address)); got != want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	var requests int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer internal.Close()
+
+	_, err := NewClient(time.Second).Get(internal.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Get() error = %v, want %v", err, ErrForbiddenAddress)
+	}
+	if requests != 0 {
+		t.Fatalf("internal server received %d requests", requests)
+	}
+
+	if err := ValidateURL(context.Background(), internal.URL); !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("ValidateURL() error = %v, want %v", err, ErrForbiddenAddress)
+	}
+}
@@ -10,12 +10,21 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/swarajkumarsingh/turbo-deploy/authentication"
+	"github.com/swarajkumarsingh/turbo-deploy/conf"
 	"github.com/swarajkumarsingh/turbo-deploy/constants"
 	"github.com/swarajkumarsingh/turbo-deploy/controller/prometheus"
+	"github.com/swarajkumarsingh/turbo-deploy/functions/audit"
 	"github.com/swarajkumarsingh/turbo-deploy/functions/logger"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/artifacts"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/db"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/outbox/relay"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/purge"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/webhook"
+	auditRoutes "github.com/swarajkumarsingh/turbo-deploy/routes/audit"
 	deploymentRoutes "github.com/swarajkumarsingh/turbo-deploy/routes/deployment"
 	deploymentLogRoutes "github.com/swarajkumarsingh/turbo-deploy/routes/deployment_log"
 	projectRoutes "github.com/swarajkumarsingh/turbo-deploy/routes/project"
+	quarantineRoutes "github.com/swarajkumarsingh/turbo-deploy/routes/quarantine"
 	userRoutes "github.com/swarajkumarsingh/turbo-deploy/routes/user"
 )
 
@@ -27,7 +36,7 @@ func enableCORS() gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 		c.Writer.Header().Set("Access-Control-Allow-Headers",
-			"Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Api-Key, token, User-Agent, Referer")
+			"Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Api-Key, X-Request-Id, token, User-Agent, Referer")
 		c.Writer.Header().Set("AllowCredentials", "true")
 		c.Writer.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
 
@@ -38,6 +47,47 @@ func enableCORS() gin.HandlerFunc {
 	}
 }
 
+// startOutboxRelay runs the relay in the background, the returned channel is closed once it stopped
+func startOutboxRelay(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+
+	var publishers []relay.Publisher
+	if conf.OutboxQueueUrl != "" {
+		publishers = append(publishers, &relay.SQSPublisher{QueueURL: conf.OutboxQueueUrl})
+	}
+	if conf.OutboxRedisChannel != "" {
+		publishers = append(publishers, &relay.RedisPublisher{Channel: conf.OutboxRedisChannel})
+	}
+	// webhooks go last, their deliveries are not retried with the record
+	publishers = append(publishers, &relay.WebhookPublisher{Dispatcher: webhook.NewDispatcher(db.Mgr.DBConn)})
+
+	go func() {
+		defer close(done)
+		relay.New(db.Mgr.DBConn, publishers...).Run(ctx)
+	}()
+	return done
+}
+
+// startTrashPurge removes soft deleted rows past their retention window in the background
+func startTrashPurge(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		purge.New(db.Mgr.DBConn).Run(ctx)
+	}()
+	return done
+}
+
+// startArtifactGC deletes build outputs outside the projects' retention policies in the background
+func startArtifactGC(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		artifacts.NewGC(db.Mgr.DBConn).Run(ctx)
+	}()
+	return done
+}
+
 func main() {
 	if constants.STAGE == constants.ENV_PROD {
 		gin.SetMode(gin.ReleaseMode)
@@ -47,6 +97,7 @@ func main() {
 
 	// Custom middleware
 	r.Use(enableCORS())
+	r.Use(audit.Middleware())
 	r.Use(prometheus.CustomMetricsMiddleware())
 	r.Use(authentication.RateLimit())
 
@@ -74,6 +125,14 @@ func main() {
 	projectRoutes.AddRoutes(r)
 	deploymentRoutes.AddRoutes(r)
 	deploymentLogRoutes.AddRoutes(r)
+	quarantineRoutes.AddRoutes(r)
+	auditRoutes.AddRoutes(r)
+
+	// Relay deployment events recorded in the outbox, purge the trash and collect expired build outputs
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	relayDone := startOutboxRelay(backgroundCtx)
+	purgeDone := startTrashPurge(backgroundCtx)
+	gcDone := startArtifactGC(backgroundCtx)
 
 	// Create server
 	srv := &http.Server{
@@ -105,5 +164,10 @@ func main() {
 		log.Panicf("Server shutdown failed: %v", err)
 	}
 
+	stopBackground()
+	<-relayDone
+	<-purgeDone
+	<-gcDone
+
 	log.Println("Server gracefully stopped")
 }
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS users;
+DROP TYPE IF EXISTS plan_type_enum;
+DROP TYPE IF EXISTS user_role_enum;
+DROP TYPE IF EXISTS primary_goal_enum;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS projects;
+DROP TYPE IF EXISTS language_enum;
+DROP TYPE IF EXISTS source_code_enum;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS deployments;
+DROP TYPE IF EXISTS status_enum;
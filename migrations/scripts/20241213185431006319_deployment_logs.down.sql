@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS deployment_logs;
+DROP TYPE IF EXISTS log_type_enum;
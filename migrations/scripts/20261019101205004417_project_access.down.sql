@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS project_access;
+DROP TYPE IF EXISTS access_mode_enum;
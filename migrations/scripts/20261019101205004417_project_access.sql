@@ -0,0 +1,12 @@
+CREATE TYPE access_mode_enum AS ENUM ('none', 'basic', 'password');
+
+CREATE TABLE IF NOT EXISTS project_access (
+    project_id INT PRIMARY KEY,
+    access_mode access_mode_enum DEFAULT 'none' NOT NULL,
+    username VARCHAR(100) DEFAULT '' NOT NULL,
+    password_hash VARCHAR(200) DEFAULT '' NOT NULL,
+    allowed_cidrs TEXT[] DEFAULT '{}' NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT fk_project FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
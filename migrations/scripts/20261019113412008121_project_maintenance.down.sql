@@ -0,0 +1,2 @@
+ALTER TABLE projects DROP COLUMN IF EXISTS maintenance_page;
+ALTER TABLE projects DROP COLUMN IF EXISTS maintenance_mode;
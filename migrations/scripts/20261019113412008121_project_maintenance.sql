@@ -0,0 +1,2 @@
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS maintenance_mode BOOLEAN DEFAULT FALSE NOT NULL;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS maintenance_page TEXT DEFAULT '' NOT NULL;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS access_logs (
+    id BIGSERIAL PRIMARY KEY,
+    project_id INT NOT NULL,
+    host VARCHAR(255) NOT NULL,
+    path TEXT NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    status INT NOT NULL,
+    bytes BIGINT DEFAULT 0 NOT NULL,
+    latency_ms INT DEFAULT 0 NOT NULL,
+    user_agent TEXT DEFAULT '',
+    referer TEXT DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT fk_project FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_access_logs_project_created_at ON access_logs (project_id, created_at);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS project_access_proxy_routes ON project_access;
+DROP TRIGGER IF EXISTS deployments_proxy_routes ON deployments;
+DROP TRIGGER IF EXISTS projects_proxy_routes ON projects;
+DROP FUNCTION IF EXISTS notify_proxy_routes();
@@ -0,0 +1,37 @@
+-- notify the proxy whenever the routing of a project may have changed, the payload is the project id
+CREATE OR REPLACE FUNCTION notify_proxy_routes() RETURNS TRIGGER AS $$
+DECLARE
+    changed_row RECORD;
+    changed_project_id INT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed_row := OLD;
+    ELSE
+        changed_row := NEW;
+    END IF;
+
+    IF TG_TABLE_NAME = 'projects' THEN
+        changed_project_id := changed_row.id;
+    ELSE
+        changed_project_id := changed_row.project_id;
+    END IF;
+
+    PERFORM pg_notify('proxy_routes', changed_project_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS projects_proxy_routes ON projects;
+CREATE TRIGGER projects_proxy_routes
+    AFTER INSERT OR UPDATE OR DELETE ON projects
+    FOR EACH ROW EXECUTE FUNCTION notify_proxy_routes();
+
+DROP TRIGGER IF EXISTS deployments_proxy_routes ON deployments;
+CREATE TRIGGER deployments_proxy_routes
+    AFTER INSERT OR UPDATE OF status OR DELETE ON deployments
+    FOR EACH ROW EXECUTE FUNCTION notify_proxy_routes();
+
+DROP TRIGGER IF EXISTS project_access_proxy_routes ON project_access;
+CREATE TRIGGER project_access_proxy_routes
+    AFTER INSERT OR UPDATE OR DELETE ON project_access
+    FOR EACH ROW EXECUTE FUNCTION notify_proxy_routes();
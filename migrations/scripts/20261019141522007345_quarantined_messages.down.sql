@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS quarantined_messages;
+DROP TYPE IF EXISTS quarantine_status_enum;
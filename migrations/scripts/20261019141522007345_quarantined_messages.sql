@@ -0,0 +1,16 @@
+CREATE TYPE quarantine_status_enum AS ENUM ('QUARANTINED', 'REDRIVEN');
+
+CREATE TABLE IF NOT EXISTS quarantined_messages (
+    id BIGSERIAL PRIMARY KEY,
+    source_queue VARCHAR(255) NOT NULL,
+    message_id VARCHAR(128) NOT NULL,
+    message_group_id VARCHAR(128) DEFAULT '' NOT NULL,
+    body TEXT NOT NULL,
+    error TEXT DEFAULT '' NOT NULL,
+    receive_count INT DEFAULT 0 NOT NULL,
+    status quarantine_status_enum DEFAULT 'QUARANTINED' NOT NULL,
+    redriven_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_quarantined_messages_status_created_at ON quarantined_messages (status, created_at);
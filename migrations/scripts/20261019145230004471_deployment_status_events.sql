@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS deployment_status_events (
+    id BIGSERIAL PRIMARY KEY,
+    deployment_id INT NOT NULL,
+    from_status status_enum NOT NULL,
+    to_status status_enum NOT NULL,
+    message_id VARCHAR(128) DEFAULT '' NOT NULL,
+    reported_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT fk_deployment FOREIGN KEY (deployment_id) REFERENCES deployments(id) ON DELETE CASCADE,
+    CONSTRAINT uq_deployment_status_events_transition UNIQUE (deployment_id, to_status)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INT PRIMARY KEY,
+    deployment_success BOOLEAN DEFAULT TRUE NOT NULL,
+    deployment_failure BOOLEAN DEFAULT TRUE NOT NULL,
+    deployment_cancelled BOOLEAN DEFAULT TRUE NOT NULL,
+    quota_warning BOOLEAN DEFAULT TRUE NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notification_channels;
+DROP TYPE IF EXISTS channel_type_enum;
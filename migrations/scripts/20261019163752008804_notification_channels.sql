@@ -0,0 +1,16 @@
+CREATE TYPE channel_type_enum AS ENUM ('slack', 'discord', 'chat', 'email');
+
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id SERIAL PRIMARY KEY,
+    project_id INT NOT NULL,
+    type channel_type_enum NOT NULL,
+    target TEXT NOT NULL,
+    on_success BOOLEAN DEFAULT TRUE NOT NULL,
+    on_failure BOOLEAN DEFAULT TRUE NOT NULL,
+    active BOOLEAN DEFAULT TRUE NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT fk_project FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_channels_project_id ON notification_channels(project_id);
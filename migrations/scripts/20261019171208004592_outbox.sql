@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate_type VARCHAR(64) NOT NULL,
+    aggregate_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(128) NOT NULL,
+    dedup_id VARCHAR(128) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT DEFAULT 0 NOT NULL,
+    last_error TEXT DEFAULT '' NOT NULL,
+    available_at TIMESTAMP DEFAULT NOW() NOT NULL,
+    published_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT uq_outbox_dedup_id UNIQUE (dedup_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_unpublished ON outbox(available_at, id) WHERE published_at IS NULL;
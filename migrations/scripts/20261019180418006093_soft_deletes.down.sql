@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS deployments_proxy_routes ON deployments;
+CREATE TRIGGER deployments_proxy_routes
+    AFTER INSERT OR UPDATE OF status OR DELETE ON deployments
+    FOR EACH ROW EXECUTE FUNCTION notify_proxy_routes();
+
+DROP INDEX IF EXISTS idx_deployments_deleted_at;
+DROP INDEX IF EXISTS idx_projects_deleted_at;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+
+ALTER TABLE deployments DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE projects DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
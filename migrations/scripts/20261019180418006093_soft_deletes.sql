@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- the purge job scans the trash by deletion time
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_projects_deleted_at ON projects(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_deployments_deleted_at ON deployments(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- soft deleting a deployment changes what the proxy serves
+DROP TRIGGER IF EXISTS deployments_proxy_routes ON deployments;
+CREATE TRIGGER deployments_proxy_routes
+    AFTER INSERT OR UPDATE OF status, deleted_at OR DELETE ON deployments
+    FOR EACH ROW EXECUTE FUNCTION notify_proxy_routes();
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS artifact_gc_runs;
+
+ALTER TABLE deployments DROP COLUMN IF EXISTS artifacts_reclaimed_bytes;
+ALTER TABLE deployments DROP COLUMN IF EXISTS artifacts_deleted_at;
+
+ALTER TABLE projects DROP COLUMN IF EXISTS retention_fail_days;
+ALTER TABLE projects DROP COLUMN IF EXISTS retention_keep_ready;
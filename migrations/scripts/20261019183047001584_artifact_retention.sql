@@ -0,0 +1,18 @@
+-- keep the outputs of the last retention_keep_ready READY deployments, the newest one is the served deployment
+-- so at least one is always kept; FAIL outputs are dropped after retention_fail_days
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS retention_keep_ready INT DEFAULT 5 NOT NULL CHECK (retention_keep_ready >= 1);
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS retention_fail_days INT DEFAULT 7 NOT NULL CHECK (retention_fail_days >= 1);
+
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS artifacts_deleted_at TIMESTAMP;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS artifacts_reclaimed_bytes BIGINT DEFAULT 0 NOT NULL;
+
+CREATE TABLE IF NOT EXISTS artifact_gc_runs (
+    id BIGSERIAL PRIMARY KEY,
+    deployments INT DEFAULT 0 NOT NULL,
+    objects INT DEFAULT 0 NOT NULL,
+    reclaimed_bytes BIGINT DEFAULT 0 NOT NULL,
+    failures INT DEFAULT 0 NOT NULL,
+    error TEXT DEFAULT '' NOT NULL,
+    started_at TIMESTAMP DEFAULT NOW() NOT NULL,
+    finished_at TIMESTAMP
+);
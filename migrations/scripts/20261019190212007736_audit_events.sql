@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    actor_type VARCHAR(16) NOT NULL,
+    actor_id VARCHAR(64) DEFAULT '' NOT NULL,
+    action VARCHAR(128) NOT NULL,
+    resource_type VARCHAR(64) DEFAULT '' NOT NULL,
+    resource_id VARCHAR(64) DEFAULT '' NOT NULL,
+    before JSONB DEFAULT '{}' NOT NULL,
+    after JSONB DEFAULT '{}' NOT NULL,
+    diff JSONB DEFAULT '{}' NOT NULL,
+    ip VARCHAR(64) DEFAULT '' NOT NULL,
+    user_agent TEXT DEFAULT '' NOT NULL,
+    request_id VARCHAR(128) DEFAULT '' NOT NULL,
+    method VARCHAR(10) DEFAULT '' NOT NULL,
+    path TEXT DEFAULT '' NOT NULL,
+    status INT DEFAULT 0 NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_resource ON audit_events(resource_type, resource_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
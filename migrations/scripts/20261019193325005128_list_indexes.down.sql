@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_deployment_logs_deployment_id_id;
+DROP INDEX IF EXISTS idx_deployments_user_id_id;
+DROP INDEX IF EXISTS idx_projects_user_id_id;
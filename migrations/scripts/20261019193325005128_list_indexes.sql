@@ -0,0 +1,4 @@
+-- keyset pagination walks these lists by id within a user or deployment
+CREATE INDEX IF NOT EXISTS idx_projects_user_id_id ON projects(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_deployments_user_id_id ON deployments(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_deployment_logs_deployment_id_id ON deployment_logs(deployment_id, id);
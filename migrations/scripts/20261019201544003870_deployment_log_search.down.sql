@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_deployment_logs_project_id_id;
+DROP INDEX IF EXISTS idx_deployment_logs_search_vector;
+ALTER TABLE deployment_logs DROP COLUMN IF EXISTS search_vector;
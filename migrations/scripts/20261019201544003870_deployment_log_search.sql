@@ -0,0 +1,8 @@
+-- the simple config keeps identifiers, paths and error codes as written instead of stemming them
+ALTER TABLE deployment_logs ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(message, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(stack, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_deployment_logs_search_vector ON deployment_logs USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_deployment_logs_project_id_id ON deployment_logs(project_id, id);
@@ -0,0 +1,5 @@
+ALTER TABLE projects DROP COLUMN IF EXISTS runtime_version;
+ALTER TABLE projects DROP COLUMN IF EXISTS root_dir;
+ALTER TABLE projects DROP COLUMN IF EXISTS output_dir;
+ALTER TABLE projects DROP COLUMN IF EXISTS build_command;
+ALTER TABLE projects DROP COLUMN IF EXISTS install_command;
@@ -0,0 +1,7 @@
+-- empty values fall back to the builder defaults: npm install, npm run build, the first of dist, build, public
+-- or release, the repository root and the node version of the builder image
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS install_command VARCHAR(512) DEFAULT '' NOT NULL;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS build_command VARCHAR(512) DEFAULT '' NOT NULL;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS output_dir VARCHAR(255) DEFAULT '' NOT NULL;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS root_dir VARCHAR(255) DEFAULT '' NOT NULL;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS runtime_version VARCHAR(16) DEFAULT '' NOT NULL;
@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS deployments_proxy_routes ON deployments;
+CREATE TRIGGER deployments_proxy_routes
+    AFTER INSERT OR UPDATE OF status, deleted_at OR DELETE ON deployments
+    FOR EACH ROW EXECUTE FUNCTION notify_proxy_routes();
+
+ALTER TABLE deployments DROP COLUMN IF EXISTS promoted_at;
+ALTER TABLE deployments DROP COLUMN IF EXISTS target;
+ALTER TABLE deployments DROP COLUMN IF EXISTS redeploy_of;
+ALTER TABLE deployments DROP COLUMN IF EXISTS source;
+ALTER TABLE deployments DROP COLUMN IF EXISTS settings;
+ALTER TABLE deployments DROP COLUMN IF EXISTS branch;
+ALTER TABLE deployments DROP COLUMN IF EXISTS commit_sha;
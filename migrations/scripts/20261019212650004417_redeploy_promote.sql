@@ -0,0 +1,19 @@
+-- what a deployment built, so it can be rebuilt exactly: commit_sha and branch are reported by the builder
+-- once it checked out the repository, settings is the project build settings at creation
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS commit_sha VARCHAR(40) DEFAULT '' NOT NULL;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS branch VARCHAR(255) DEFAULT '' NOT NULL;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS settings JSONB DEFAULT '{}' NOT NULL;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS source VARCHAR(16) DEFAULT 'git' NOT NULL
+    CHECK (source IN ('git', 'static_upload', 'source_upload'));
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS redeploy_of INT REFERENCES deployments(id) ON DELETE SET NULL;
+
+-- the proxy serves the READY production deployment with the latest COALESCE(promoted_at, created_at),
+-- promoting a preview or an older production deployment makes it the served one without a rebuild
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS target VARCHAR(16) DEFAULT 'production' NOT NULL
+    CHECK (target IN ('production', 'preview'));
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS promoted_at TIMESTAMP;
+
+DROP TRIGGER IF EXISTS deployments_proxy_routes ON deployments;
+CREATE TRIGGER deployments_proxy_routes
+    AFTER INSERT OR UPDATE OF status, deleted_at, target, promoted_at OR DELETE ON deployments
+    FOR EACH ROW EXECUTE FUNCTION notify_proxy_routes();
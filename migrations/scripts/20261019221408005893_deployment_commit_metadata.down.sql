@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_deployments_user_id_commit_sha;
+
+ALTER TABLE deployments DROP COLUMN IF EXISTS trigger_source;
+ALTER TABLE deployments DROP COLUMN IF EXISTS commit_author;
+ALTER TABLE deployments DROP COLUMN IF EXISTS commit_message;
@@ -0,0 +1,10 @@
+-- commit metadata shown on deployments and in notifications, set from the create request or reported by
+-- the builder once it checked out the repository, and what triggered the deployment
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS commit_message TEXT DEFAULT '' NOT NULL;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS commit_author VARCHAR(255) DEFAULT '' NOT NULL;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS trigger_source VARCHAR(16) DEFAULT 'manual' NOT NULL
+    CHECK (trigger_source IN ('manual', 'webhook', 'api_token', 'redeploy'));
+
+UPDATE deployments SET trigger_source = 'redeploy' WHERE redeploy_of IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_deployments_user_id_commit_sha ON deployments(user_id, commit_sha text_pattern_ops);
@@ -0,0 +1,4 @@
+ALTER TABLE notification_channels DROP CONSTRAINT IF EXISTS chk_notification_channels_verified;
+ALTER TABLE notification_channels DROP COLUMN IF EXISTS verified_at;
+ALTER TABLE notification_channels DROP COLUMN IF EXISTS verification_expires_at;
+ALTER TABLE notification_channels DROP COLUMN IF EXISTS verification_code_hash;
@@ -0,0 +1,10 @@
+ALTER TABLE notification_channels ADD COLUMN IF NOT EXISTS verification_code_hash VARCHAR(64);
+ALTER TABLE notification_channels ADD COLUMN IF NOT EXISTS verification_expires_at TIMESTAMP;
+ALTER TABLE notification_channels ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP;
+
+-- webhook channels need no verification, email channels created so far have to be verified before they send again
+UPDATE notification_channels SET verified_at = created_at WHERE type <> 'email';
+UPDATE notification_channels SET active = FALSE WHERE type = 'email';
+
+ALTER TABLE notification_channels ADD CONSTRAINT chk_notification_channels_verified
+    CHECK (NOT active OR verified_at IS NOT NULL);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS uq_quarantined_messages_message_id;
@@ -0,0 +1,5 @@
+-- keep the first copy of messages stored more than once
+DELETE FROM quarantined_messages q USING quarantined_messages dup
+    WHERE q.source_queue = dup.source_queue AND q.message_id = dup.message_id AND q.id > dup.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_quarantined_messages_message_id ON quarantined_messages (source_queue, message_id);
@@ -0,0 +1,50 @@
+package accesslog
+
+import (
+	"context"
+	"time"
+
+	"github.com/swarajkumarsingh/turbo-deploy/infra/db"
+)
+
+var database = db.Mgr.DBConn
+
+// a page view is a successful request for an html document, either by extension or an extension-less route
+const pageViewCondition = `status < 400 AND method = 'GET' AND (path LIKE '%.html' OR path !~ '\.[a-zA-Z0-9]+$')`
+
+func GetSummary(ctx context.Context, projectId int, from, to time.Time) (Summary, error) {
+	var summary Summary
+	query := `SELECT COUNT(*) AS requests, COUNT(*) FILTER (WHERE ` + pageViewCondition + `) AS page_views,
+		COALESCE(SUM(bytes), 0) AS bytes, COALESCE(AVG(latency_ms), 0) AS avg_latency_ms
+		FROM access_logs WHERE project_id = $1 AND created_at >= $2 AND created_at < $3`
+	err := database.GetContext(ctx, &summary, query, projectId, from, to)
+	return summary, err
+}
+
+func GetTopPaths(ctx context.Context, projectId int, from, to time.Time, limit int) ([]PathCount, error) {
+	paths := make([]PathCount, 0)
+	query := `SELECT path, COUNT(*) AS count FROM access_logs
+		WHERE project_id = $1 AND created_at >= $2 AND created_at < $3 AND ` + pageViewCondition + `
+		GROUP BY path ORDER BY count DESC, path LIMIT $4`
+	err := database.SelectContext(ctx, &paths, query, projectId, from, to, limit)
+	return paths, err
+}
+
+func GetStatusDistribution(ctx context.Context, projectId int, from, to time.Time) ([]StatusCount, error) {
+	statuses := make([]StatusCount, 0)
+	query := `SELECT (status / 100)::text || 'xx' AS status_class, COUNT(*) AS count FROM access_logs
+		WHERE project_id = $1 AND created_at >= $2 AND created_at < $3
+		GROUP BY status_class ORDER BY status_class`
+	err := database.SelectContext(ctx, &statuses, query, projectId, from, to)
+	return statuses, err
+}
+
+func GetDailyCounts(ctx context.Context, projectId int, from, to time.Time) ([]DailyCount, error) {
+	days := make([]DailyCount, 0)
+	query := `SELECT to_char(date_trunc('day', created_at), 'YYYY-MM-DD') AS day,
+		COUNT(*) FILTER (WHERE ` + pageViewCondition + `) AS page_views, COUNT(*) AS requests
+		FROM access_logs WHERE project_id = $1 AND created_at >= $2 AND created_at < $3
+		GROUP BY day ORDER BY day`
+	err := database.SelectContext(ctx, &days, query, projectId, from, to)
+	return days, err
+}
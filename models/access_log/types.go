@@ -0,0 +1,24 @@
+package accesslog
+
+type PathCount struct {
+	Path  string `json:"path" db:"path"`
+	Count int    `json:"count" db:"count"`
+}
+
+type StatusCount struct {
+	StatusClass string `json:"status_class" db:"status_class"`
+	Count       int    `json:"count" db:"count"`
+}
+
+type DailyCount struct {
+	Day       string `json:"day" db:"day"`
+	PageViews int    `json:"page_views" db:"page_views"`
+	Requests  int    `json:"requests" db:"requests"`
+}
+
+type Summary struct {
+	Requests     int     `json:"requests" db:"requests"`
+	PageViews    int     `json:"page_views" db:"page_views"`
+	Bytes        int64   `json:"bytes" db:"bytes"`
+	AvgLatencyMs float64 `json:"avg_latency_ms" db:"avg_latency_ms"`
+}
@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/swarajkumarsingh/turbo-deploy/infra/db"
+)
+
+var database = db.Mgr.DBConn
+
+// listCondition applies an AuditFilter, empty values match everything
+const listCondition = `($1 = '' OR resource_type = $1) AND ($2 = '' OR resource_id = $2) AND ($3 = '' OR actor_id = $3)
+	AND ($4 = '' OR action = $4) AND created_at >= $5 AND created_at < $6`
+
+func InsertAuditEvents(ctx context.Context, events []AuditEvent) error {
+	query := `INSERT INTO audit_events(actor_type, actor_id, action, resource_type, resource_id, before, after, diff,
+		ip, user_agent, request_id, method, path, status)
+		VALUES(:actor_type, :actor_id, :action, :resource_type, :resource_id, :before, :after, :diff,
+		:ip, :user_agent, :request_id, :method, :path, :status)`
+	_, err := database.NamedExecContext(ctx, query, events)
+	return err
+}
+
+func GetAuditEvents(ctx context.Context, filter AuditFilter, itemsPerPage, offset int) ([]AuditEvent, error) {
+	events := make([]AuditEvent, 0)
+	query := `SELECT * FROM audit_events WHERE ` + listCondition + ` ORDER BY id DESC LIMIT $7 OFFSET $8`
+	err := database.SelectContext(ctx, &events, query, filter.ResourceType, filter.ResourceId, filter.ActorId, filter.Action,
+		filter.From, filter.To, itemsPerPage, offset)
+	return events, err
+}
+
+func GetTotalAuditEventsCount(ctx context.Context, filter AuditFilter) (int, error) {
+	var total int
+	query := `SELECT COUNT(*) FROM audit_events WHERE ` + listCondition
+	err := database.GetContext(ctx, &total, query, filter.ResourceType, filter.ResourceId, filter.ActorId, filter.Action,
+		filter.From, filter.To)
+	return total, err
+}
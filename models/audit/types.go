@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+const ActorUser = "user"
+const ActorAdmin = "admin"
+const ActorAnonymous = "anonymous"
+
+type AuditEvent struct {
+	Id           int64          `json:"id" db:"id"`
+	ActorType    string         `json:"actor_type" db:"actor_type"`
+	ActorId      string         `json:"actor_id" db:"actor_id"`
+	Action       string         `json:"action" db:"action"`
+	ResourceType string         `json:"resource_type" db:"resource_type"`
+	ResourceId   string         `json:"resource_id" db:"resource_id"`
+	Before       types.JSONText `json:"before" db:"before"`
+	After        types.JSONText `json:"after" db:"after"`
+	Diff         types.JSONText `json:"diff" db:"diff"`
+	IP           string         `json:"ip" db:"ip"`
+	UserAgent    string         `json:"user_agent" db:"user_agent"`
+	RequestId    string         `json:"request_id" db:"request_id"`
+	Method       string         `json:"method" db:"method"`
+	Path         string         `json:"path" db:"path"`
+	Status       int            `json:"status" db:"status"`
+	CreatedAt    string         `json:"created_on" db:"created_at"`
+}
+
+// AuditFilter narrows the audit events listed, empty fields match everything
+type AuditFilter struct {
+	ResourceType string
+	ResourceId   string
+	ActorId      string
+	Action       string
+	From         time.Time
+	To           time.Time
+}
@@ -3,8 +3,10 @@ package deployment
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/swarajkumarsingh/turbo-deploy/infra/db"
 	projectModel "github.com/swarajkumarsingh/turbo-deploy/models/project"
@@ -14,7 +16,7 @@ var database = db.Mgr.DBConn
 
 func GetProjectById(context context.Context, pid string) (projectModel.Project, error) {
 	var model projectModel.Project
-	query := "SELECT * FROM projects WHERE id = $1"
+	query := "SELECT * FROM projects WHERE id = $1 AND deleted_at IS NULL"
 	err := database.GetContext(context, &model, query, pid)
 	if err == nil {
 		return model, nil
@@ -42,19 +44,42 @@ func CreateDeployment(ctx context.Context, projectId int, userId string) (int, e
 	return deploymentId, nil
 }
 
-func CreateDeploymentTx(ctx context.Context, tx *sql.Tx, projectId int, userId string) (int, error) {
+func CreateDeploymentTx(ctx context.Context, tx *sql.Tx, projectId int, userId string, spec DeploymentSpec) (int, error) {
+	settings, err := json.Marshal(spec.Settings)
+	if err != nil {
+		return 0, err
+	}
+
 	var deploymentId int
-	query := `INSERT INTO deployments(user_id, project_id) VALUES($1, $2) RETURNING id`
-	err := tx.QueryRowContext(ctx, query, userId, projectId).Scan(&deploymentId)
+	query := `INSERT INTO deployments(user_id, project_id, source, target, branch, commit_sha, commit_message, commit_author,
+			trigger_source, settings, redeploy_of)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	err = tx.QueryRowContext(ctx, query, userId, projectId, spec.Source, spec.Target, spec.Branch, spec.CommitSha,
+		spec.CommitMessage, spec.CommitAuthor, spec.Trigger, string(settings), spec.RedeployOf).Scan(&deploymentId)
 	if err != nil {
 		return 0, err
 	}
 	return deploymentId, nil
 }
 
+// PromoteDeployment makes a READY deployment whose outputs still exist the served production deployment,
+// a preview takes over the ready url of the project. It returns false when the deployment cannot be promoted
+func PromoteDeployment(ctx context.Context, id int) (bool, error) {
+	query := `UPDATE deployments d SET target = 'production', promoted_at = NOW(), updated_at = NOW(),
+		ready_url = COALESCE((SELECT other.ready_url FROM deployments other WHERE other.project_id = d.project_id
+			AND other.target = 'production' AND COALESCE(other.ready_url, '') <> '' ORDER BY other.id DESC LIMIT 1), d.ready_url)
+		WHERE d.id = $1 AND d.status = 'READY' AND d.deleted_at IS NULL AND d.artifacts_deleted_at IS NULL`
+	result, err := database.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
 func GetDeploymentById(context context.Context, id int) (Deployment, error) {
 	var model Deployment
-	query := "SELECT * FROM deployments WHERE id = $1"
+	query := "SELECT * FROM deployments WHERE id = $1 AND deleted_at IS NULL"
 	err := database.GetContext(context, &model, query, id)
 	if err == nil {
 		return model, nil
@@ -64,7 +89,7 @@ func GetDeploymentById(context context.Context, id int) (Deployment, error) {
 
 func GetDeploymentStatus(context context.Context, id int) (string, error) {
 	var status string
-	query := "SELECT status FROM deployments WHERE id = $1"
+	query := "SELECT status FROM deployments WHERE id = $1 AND deleted_at IS NULL"
 	err := database.GetContext(context, &status, query, id)
 	if err == nil {
 		return status, nil
@@ -72,13 +97,29 @@ func GetDeploymentStatus(context context.Context, id int) (string, error) {
 	return status, err
 }
 
-func GetDeploymentListPaginatedValue(context context.Context, uid string, itemsPerPage, offset int) (*sql.Rows, error) {
-	query := `SELECT id, project_id, status, ready_url FROM deployments WHERE user_id = $1 ORDER BY id LIMIT $2 OFFSET $3`
-	return database.QueryContext(context, query, uid, itemsPerPage, offset)
+// GetDeploymentListAfter lists the user's deployments with an id greater than after in id order
+func GetDeploymentListAfter(context context.Context, uid string, filter DeploymentFilter, after int64, limit int) (*sql.Rows, error) {
+	query := `SELECT id, project_id, status, COALESCE(ready_url, ''), commit_sha, branch, commit_message, commit_author, trigger_source
+		FROM deployments WHERE user_id = $1 AND deleted_at IS NULL AND id > $2
+		AND ($3 = 0 OR project_id = $3) AND ($4 = '' OR status::text = $4)
+		AND ($5::timestamp IS NULL OR created_at >= $5) AND ($6::timestamp IS NULL OR created_at < $6)
+		AND ($7 = '' OR branch = $7) AND ($8 = '' OR commit_sha LIKE $8 || '%') AND ($9 = '' OR trigger_source = $9)
+		ORDER BY id LIMIT $10`
+	return database.QueryContext(context, query, uid, after, filter.ProjectId, filter.Status,
+		nullTime(filter.From), nullTime(filter.To), filter.Branch, filter.CommitSha, filter.TriggerSource, limit)
 }
 
+// nullTime passes the zero time as NULL so the filter is skipped
+func nullTime(value time.Time) interface{} {
+	if value.IsZero() {
+		return nil
+	}
+	return value
+}
+
+// DeleteDeploymentFromUser moves the deployment to the trash, the purge job removes its row, logs and build outputs
 func DeleteDeploymentFromUser(ctx context.Context, pid int) error {
-	query := "DELETE from deployments WHERE id = $1"
+	query := "UPDATE deployments SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL"
 
 	result, err := database.ExecContext(ctx, query, pid)
 	if err != nil {
@@ -96,33 +137,8 @@ func DeleteDeploymentFromUser(ctx context.Context, pid int) error {
 	return nil
 }
 
-func GetAllDeploymentIDsByUser(ctx context.Context, uid string) ([]int, error) {
-	query := "SELECT id FROM deployments WHERE user_id = $1"
-
-	rows, err := database.QueryContext(ctx, query, uid)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch deployment IDs: %w", err)
-	}
-	defer rows.Close()
-
-	var ids []int
-	for rows.Next() {
-		var id int
-		if err := rows.Scan(&id); err != nil {
-			return nil, fmt.Errorf("failed to scan deployment ID: %w", err)
-		}
-		ids = append(ids, id)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating through rows: %w", err)
-	}
-
-	return ids, nil
-}
-
 func DeleteAllDeploymentFromUser(ctx context.Context, uid string) error {
-	query := "DELETE from deployments WHERE user_id = $1"
+	query := "UPDATE deployments SET deleted_at = NOW() WHERE user_id = $1 AND deleted_at IS NULL"
 
 	result, err := database.ExecContext(ctx, query, uid)
 	if err != nil {
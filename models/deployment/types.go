@@ -1,17 +1,92 @@
 package deployment
 
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+	projectModel "github.com/swarajkumarsingh/turbo-deploy/models/project"
+)
+
+const (
+	SourceGit          = "git"
+	SourceStaticUpload = "static_upload"
+	SourceSourceUpload = "source_upload"
+
+	TargetProduction = "production"
+	TargetPreview    = "preview"
+
+	// Trigger sources record what started a deployment
+	TriggerManual   = "manual"
+	TriggerWebhook  = "webhook"
+	TriggerApiToken = "api_token"
+	TriggerRedeploy = "redeploy"
+)
+
 type DeploymentBody struct {
 	ProjectId string `validate:"required" json:"projectId"`
+	// Branch builds a branch other than the default one, Target defaults to production
+	Branch string `json:"branch"`
+	Target string `json:"target" validate:"omitempty,oneof=production preview"`
+	// CommitSha pins the build to a commit, the message and author are shown until the builder reports them
+	CommitSha     string `json:"commit_sha"`
+	CommitMessage string `json:"commit_message"`
+	CommitAuthor  string `json:"commit_author"`
+}
+
+type RedeployBody struct {
+	ClearCache bool `json:"clear_cache"`
+}
+
+// DeploymentSpec is what a new deployment builds
+type DeploymentSpec struct {
+	Source        string
+	Target        string
+	Branch        string
+	CommitSha     string
+	CommitMessage string
+	CommitAuthor  string
+	Trigger       string
+	Settings      projectModel.BuildSettings
+	RedeployOf    *int
+}
+
+// DeploymentFilter narrows deployment lists, zero values match everything
+type DeploymentFilter struct {
+	ProjectId int
+	Status    string
+	From      time.Time
+	To        time.Time
+	Branch    string
+	// CommitSha matches commits starting with it, so short shas work
+	CommitSha     string
+	TriggerSource string
 }
 
 type Deployment struct {
-	Id        int    `json:"id" db:"id"`
-	UserId    string `json:"user_id" db:"user_id"`
-	ProjectId string `json:"project_id" db:"project_id"`
-	Duration  string `json:"duration" db:"duration"`
-	ReadUrl   string `json:"ready_url" db:"ready_url"`
-	LastLog   string `json:"last_log" db:"last_log"`
-	Status    string `json:"status" db:"status"`
-	CreatedAt string `json:"created_on" db:"created_at"`
-	UpdatedAt string `json:"updated_at" db:"updated_at"`
+	Id        int     `json:"id" db:"id"`
+	UserId    string  `json:"user_id" db:"user_id"`
+	ProjectId string  `json:"project_id" db:"project_id"`
+	Duration  string  `json:"duration" db:"duration"`
+	ReadUrl   string  `json:"ready_url" db:"ready_url"`
+	LastLog   string  `json:"last_log" db:"last_log"`
+	Status    string  `json:"status" db:"status"`
+	CreatedAt string  `json:"created_on" db:"created_at"`
+	UpdatedAt string  `json:"updated_at" db:"updated_at"`
+	DeletedAt *string `json:"deleted_at,omitempty" db:"deleted_at"`
+	// ArtifactsDeletedAt is set once the artifact GC removed the build outputs
+	ArtifactsDeletedAt      *string `json:"artifacts_deleted_at,omitempty" db:"artifacts_deleted_at"`
+	ArtifactsReclaimedBytes int64   `json:"artifacts_reclaimed_bytes" db:"artifacts_reclaimed_bytes"`
+	// What was built, Settings is a snapshot of the project build settings
+	CommitSha  string         `json:"commit_sha" db:"commit_sha"`
+	Branch     string         `json:"branch" db:"branch"`
+	Settings   types.JSONText `json:"settings" db:"settings"`
+	Source     string         `json:"source" db:"source"`
+	RedeployOf *int           `json:"redeploy_of" db:"redeploy_of"`
+	// Target is production or preview, PromotedAt is set when the deployment was promoted to be served
+	Target     string  `json:"target" db:"target"`
+	PromotedAt *string `json:"promoted_at" db:"promoted_at"`
+	// Commit details are empty until known, TriggerSource is one of the Trigger constants
+	CommitMessage string `json:"commit_message" db:"commit_message"`
+	CommitAuthor  string `json:"commit_author" db:"commit_author"`
+	TriggerSource string `json:"trigger_source" db:"trigger_source"`
 }
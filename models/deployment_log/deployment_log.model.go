@@ -3,23 +3,65 @@ package deploymentlog
 import (
 	"context"
 	"database/sql"
+	"html"
+	"strings"
 
 	"github.com/swarajkumarsingh/turbo-deploy/infra/db"
 )
 
 var database = db.Mgr.DBConn
 
-func GetDeploymentLogsPaginatedValue(context context.Context, deployment_id, itemsPerPage, offset int) (*sql.Rows, error) {
-	query := `SELECT id, deployment_id, project_id, message, stack, log_type, timestamp FROM deployment_logs WHERE deployment_id = $1 ORDER BY id LIMIT $2 OFFSET $3`
-	return database.QueryContext(context, query, deployment_id, itemsPerPage, offset)
+// ts_headline wraps matches in control characters that are stripped from the logs beforehand,
+// they become <mark> tags once the snippet itself has been escaped
+const (
+	matchStart     = "\x01"
+	matchStop      = "\x02"
+	snippetOptions = "StartSel=" + matchStart + ", StopSel=" + matchStop + ", MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=\" … \""
+)
+
+var markReplacer = strings.NewReplacer(matchStart, "<mark>", matchStop, "</mark>")
+
+// highlight escapes a snippet for html and marks its matches
+func highlight(snippet string) string {
+	return markReplacer.Replace(html.EscapeString(snippet))
+}
+
+// GetDeploymentLogsAfter lists the logs of a deployment with an id greater than after in id order,
+// Query is matched against the search vector of message and stack
+func GetDeploymentLogsAfter(context context.Context, deployment_id int, filter DeploymentLogFilter, after int64, limit int) (*sql.Rows, error) {
+	query := `SELECT id, deployment_id, project_id, message, stack, log_type, timestamp FROM deployment_logs WHERE deployment_id = $1
+		AND EXISTS (SELECT 1 FROM deployments d WHERE d.id = deployment_id AND d.deleted_at IS NULL) AND id > $2
+		AND ($3 = '' OR log_type::text = $3) AND ($4 = '' OR search_vector @@ websearch_to_tsquery('simple', $4))
+		ORDER BY id LIMIT $5`
+	return database.QueryContext(context, query, deployment_id, after, filter.LogType, filter.Query, limit)
 }
 
-func GetTotalDeploymentLogsCount(context context.Context, deployment_id int) int {
-	var total int
-	query := `SELECT COUNT(*) FROM deployment_logs WHERE deployment_id = $1`
-	err := database.QueryRow(query, deployment_id).Scan(&total)
+// SearchDeploymentLogs returns the logs matching filter.Query in id order with highlighted
+// message and stack snippets, scoped to a deployment or to every live deployment of a project
+func SearchDeploymentLogs(context context.Context, filter LogSearchFilter, after int64, limit int) ([]LogSearchResult, error) {
+	query := `WITH q AS (SELECT websearch_to_tsquery('simple', $1) AS query)
+		SELECT l.id, l.deployment_id, l.project_id, l.log_type::text AS log_type, COALESCE(l.timestamp, '') AS timestamp,
+			ts_headline('simple', translate(COALESCE(l.message, ''), E'\x01\x02', ''), q.query, $2) AS message,
+			ts_headline('simple', translate(COALESCE(l.stack, ''), E'\x01\x02', ''), q.query, $2) AS stack,
+			ts_rank(l.search_vector, q.query) AS rank
+		FROM deployment_logs l CROSS JOIN q
+		JOIN deployments d ON d.id = l.deployment_id AND d.deleted_at IS NULL
+		JOIN projects p ON p.id = d.project_id AND p.user_id = $8 AND p.deleted_at IS NULL
+		WHERE l.search_vector @@ q.query AND l.id > $3
+		AND ($4 = 0 OR l.deployment_id = $4) AND ($5 = 0 OR l.project_id = $5)
+		AND ($6 = '' OR l.log_type::text = $6)
+		ORDER BY l.id LIMIT $7`
+
+	results := make([]LogSearchResult, 0)
+	err := database.SelectContext(context, &results, query, filter.Query, snippetOptions, after,
+		filter.DeploymentId, filter.ProjectId, filter.LogType, limit, filter.UserId)
 	if err != nil {
-		return 0
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].Message = highlight(results[i].Message)
+		results[i].Stack = highlight(results[i].Stack)
 	}
-	return total
-}
\ No newline at end of file
+	return results, nil
+}
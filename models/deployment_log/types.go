@@ -1 +1,27 @@
-package deploymentlog
\ No newline at end of file
+package deploymentlog
+
+// DeploymentLogFilter narrows log lists, empty values match everything
+type DeploymentLogFilter struct {
+	LogType string
+	Query   string
+}
+
+// LogSearchFilter scopes a search to the projects owned by UserId, optionally to one deployment or project
+type LogSearchFilter struct {
+	UserId       string
+	DeploymentId int
+	ProjectId    int
+	LogType      string
+	Query        string
+}
+
+type LogSearchResult struct {
+	Id           int64   `json:"id" db:"id"`
+	DeploymentId int     `json:"deployment_id" db:"deployment_id"`
+	ProjectId    int     `json:"project_id" db:"project_id"`
+	LogType      string  `json:"log_type" db:"log_type"`
+	Timestamp    string  `json:"timestamp" db:"timestamp"`
+	Message      string  `json:"message" db:"message"`
+	Stack        string  `json:"stack" db:"stack"`
+	Rank         float32 `json:"rank" db:"rank"`
+}
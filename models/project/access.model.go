@@ -0,0 +1,48 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+func GetProjectAccess(ctx context.Context, pid int) (ProjectAccess, error) {
+	var model ProjectAccess
+	query := "SELECT * FROM project_access WHERE project_id = $1"
+	err := database.GetContext(ctx, &model, query, pid)
+	if err == nil {
+		return model, nil
+	}
+	return model, err
+}
+
+func UpsertProjectAccess(ctx context.Context, pid int, mode, username, passwordHash string, cidrs []string) error {
+	query := `INSERT INTO project_access(project_id, access_mode, username, password_hash, allowed_cidrs) VALUES($1, $2, $3, $4, $5)
+		ON CONFLICT (project_id) DO UPDATE SET access_mode = $2, username = $3, password_hash = $4, allowed_cidrs = $5, updated_at = NOW()`
+	_, err := database.ExecContext(ctx, query, pid, mode, username, passwordHash, pq.Array(cidrs))
+	if err != nil {
+		return fmt.Errorf("failed to save project access: %w", err)
+	}
+	return nil
+}
+
+func DeleteProjectAccess(ctx context.Context, pid int) error {
+	query := "DELETE FROM project_access WHERE project_id = $1"
+
+	result, err := database.ExecContext(ctx, query, pid)
+	if err != nil {
+		return fmt.Errorf("failed to delete project access: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to fetch affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return errors.New("project access not found or already deleted")
+	}
+
+	return nil
+}
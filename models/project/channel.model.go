@@ -0,0 +1,102 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ChannelVerification is the code a channel waits for and how long it is valid, the zero value stores the
+// channel as verified
+type ChannelVerification struct {
+	CodeHash string
+	TTL      time.Duration
+}
+
+func CreateNotificationChannel(ctx context.Context, channel NotificationChannel, verification ChannelVerification) (NotificationChannel, error) {
+	var model NotificationChannel
+	query := `INSERT INTO notification_channels(project_id, type, target, on_success, on_failure, active,
+			verification_code_hash, verification_expires_at, verified_at)
+		VALUES($1, $2, $3, $4, $5, $6, NULLIF($7, ''), CASE WHEN $7 = '' THEN NULL ELSE NOW() + $8 * INTERVAL '1 second' END,
+			CASE WHEN $7 = '' THEN NOW() END)
+		RETURNING *`
+	err := database.GetContext(ctx, &model, query, channel.ProjectId, channel.Type, channel.Target,
+		channel.OnSuccess, channel.OnFailure, channel.Active, verification.CodeHash, int(verification.TTL.Seconds()))
+	if err != nil {
+		return model, fmt.Errorf("failed to create notification channel: %w", err)
+	}
+	return model, nil
+}
+
+func GetNotificationChannels(ctx context.Context, pid int) ([]NotificationChannel, error) {
+	channels := make([]NotificationChannel, 0)
+	query := "SELECT * FROM notification_channels WHERE project_id = $1 ORDER BY id"
+	err := database.SelectContext(ctx, &channels, query, pid)
+	return channels, err
+}
+
+func GetNotificationChannelById(ctx context.Context, pid, cid int) (NotificationChannel, error) {
+	var model NotificationChannel
+	query := "SELECT * FROM notification_channels WHERE id = $1 AND project_id = $2"
+	err := database.GetContext(ctx, &model, query, cid, pid)
+	return model, err
+}
+
+func UpdateNotificationChannel(ctx context.Context, channel NotificationChannel) error {
+	query := `UPDATE notification_channels SET target = $2, on_success = $3, on_failure = $4, active = $5, updated_at = NOW()
+		WHERE id = $1`
+	_, err := database.ExecContext(ctx, query, channel.Id, channel.Target, channel.OnSuccess, channel.OnFailure, channel.Active)
+	if err != nil {
+		return fmt.Errorf("failed to update notification channel: %w", err)
+	}
+	return nil
+}
+
+// ResetNotificationChannelVerification pauses the channel until the new code is verified, used when the target changes
+func ResetNotificationChannelVerification(ctx context.Context, cid int, verification ChannelVerification) error {
+	query := `UPDATE notification_channels SET active = FALSE, verified_at = NULL, verification_code_hash = $2,
+			verification_expires_at = NOW() + $3 * INTERVAL '1 second', updated_at = NOW()
+		WHERE id = $1`
+	_, err := database.ExecContext(ctx, query, cid, verification.CodeHash, int(verification.TTL.Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to reset notification channel verification: %w", err)
+	}
+	return nil
+}
+
+// VerifyNotificationChannel activates the channel when the code matches and has not expired, it reports whether it did
+func VerifyNotificationChannel(ctx context.Context, cid int, verificationCodeHash string) (bool, error) {
+	query := `UPDATE notification_channels SET active = TRUE, verified_at = NOW(), verification_code_hash = NULL,
+			verification_expires_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND verified_at IS NULL AND verification_code_hash = $2 AND verification_expires_at > NOW()`
+	result, err := database.ExecContext(ctx, query, cid, verificationCodeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify notification channel: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch affected rows: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+func DeleteNotificationChannel(ctx context.Context, pid, cid int) error {
+	query := "DELETE FROM notification_channels WHERE id = $1 AND project_id = $2"
+
+	result, err := database.ExecContext(ctx, query, cid, pid)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification channel: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to fetch affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return errors.New("notification channel not found or already deleted")
+	}
+
+	return nil
+}
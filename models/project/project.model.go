@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/swarajkumarsingh/turbo-deploy/constants/messages"
 	"github.com/swarajkumarsingh/turbo-deploy/infra/db"
 )
@@ -15,7 +17,7 @@ var database = db.Mgr.DBConn
 
 func GetProjectById(context context.Context, pid int) (Project, error) {
 	var model Project
-	query := "SELECT * FROM projects WHERE id = $1"
+	query := "SELECT * FROM projects WHERE id = $1 AND deleted_at IS NULL"
 	err := database.GetContext(context, &model, query, pid)
 	if err == nil {
 		return model, nil
@@ -23,9 +25,26 @@ func GetProjectById(context context.Context, pid int) (Project, error) {
 	return model, err
 }
 
-func GetProjectListPaginatedValue(context context.Context, uid string, itemsPerPage, offset int) (*sql.Rows, error) {
-	query := `SELECT id, name, subdomain, language FROM projects WHERE user_id = $1 ORDER BY id LIMIT $2 OFFSET $3`
-	return database.QueryContext(context, query, uid, itemsPerPage, offset)
+// GetTrashedProjectById returns a soft deleted project that is still within the retention window
+func GetTrashedProjectById(context context.Context, pid int, retention time.Duration) (Project, error) {
+	var model Project
+	query := "SELECT * FROM projects WHERE id = $1 AND deleted_at IS NOT NULL AND deleted_at > NOW() - $2 * INTERVAL '1 second'"
+	err := database.GetContext(context, &model, query, pid, retention.Seconds())
+	return model, err
+}
+
+func GetTrashedProjects(context context.Context, uid string, retention time.Duration) ([]TrashedProject, error) {
+	projects := make([]TrashedProject, 0)
+	query := `SELECT id, name, COALESCE(subdomain, '') AS subdomain, deleted_at, deleted_at + $2 * INTERVAL '1 second' AS purge_at
+		FROM projects WHERE user_id = $1 AND deleted_at IS NOT NULL AND deleted_at > NOW() - $2 * INTERVAL '1 second' ORDER BY deleted_at DESC`
+	err := database.SelectContext(context, &projects, query, uid, retention.Seconds())
+	return projects, err
+}
+
+// GetProjectListAfter lists the user's projects with an id greater than after in id order
+func GetProjectListAfter(context context.Context, uid string, after int64, limit int) (*sql.Rows, error) {
+	query := `SELECT id, name, subdomain, language FROM projects WHERE user_id = $1 AND deleted_at IS NULL AND id > $2 ORDER BY id LIMIT $3`
+	return database.QueryContext(context, query, uid, after, limit)
 }
 
 func IsSubDomainAvailable(ctx context.Context, subDomain string) (bool, error) {
@@ -38,10 +57,15 @@ func IsSubDomainAvailable(ctx context.Context, subDomain string) (bool, error) {
 	return count == 0, nil
 }
 
+// DeleteAllProjectFromUser moves every project of the user and their deployments to the trash
 func DeleteAllProjectFromUser(ctx context.Context, uid string) error {
-	query := "DELETE from projects WHERE user_id = $1"
+	tx, err := database.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	result, err := database.ExecContext(ctx, query, uid)
+	result, err := tx.ExecContext(ctx, "UPDATE projects SET deleted_at = NOW() WHERE user_id = $1 AND deleted_at IS NULL", uid)
 	if err != nil {
 		return errors.New("projects not found or already deleted")
 	}
@@ -54,13 +78,22 @@ func DeleteAllProjectFromUser(ctx context.Context, uid string) error {
 		return errors.New("projects not found or already deleted")
 	}
 
-	return nil
+	if err := trashProjectDeployments(ctx, tx, "p.user_id = $1", uid); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// DeleteProjectFromUser moves the project and its deployments to the trash, the purge job removes them
+// once the retention window expired
 func DeleteProjectFromUser(ctx context.Context, pid int) error {
-	query := "DELETE from projects WHERE id = $1"
+	tx, err := database.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	result, err := database.ExecContext(ctx, query, pid)
+	result, err := tx.ExecContext(ctx, "UPDATE projects SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", pid)
 	if err != nil {
 		return errors.New("project not found or already deleted")
 	}
@@ -73,24 +106,81 @@ func DeleteProjectFromUser(ctx context.Context, pid int) error {
 		return errors.New("project not found or already deleted")
 	}
 
+	if err := trashProjectDeployments(ctx, tx, "p.id = $1", pid); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// trashProjectDeployments stamps the live deployments of the matched projects with their project's deleted_at,
+// restoring a project brings back exactly those and leaves deployments deleted on their own in the trash
+func trashProjectDeployments(ctx context.Context, tx *sqlx.Tx, condition string, arg interface{}) error {
+	query := `UPDATE deployments d SET deleted_at = p.deleted_at FROM projects p
+		WHERE d.project_id = p.id AND d.deleted_at IS NULL AND ` + condition
+	if _, err := tx.ExecContext(ctx, query, arg); err != nil {
+		return fmt.Errorf("failed to delete deployments: %w", err)
+	}
 	return nil
 }
 
-func CreateProject(context context.Context, body ProjectBody) (bool, error) {
-	query := `INSERT INTO projects(user_id, name, source_code_url, subdomain, custom_domain, source_code, language, is_dockerized) VALUES($1, $2, $3, $4, $5, $6, $7, $8)`
-	_, err := database.ExecContext(context, query, body.UserId, body.Name, body.SourceCodeUrl, body.Subdomain, body.Subdomain, body.SourceCode, body.Language, body.IsDockerized)
+// RestoreProject takes the project and the deployments deleted along with it out of the trash,
+// sql.ErrNoRows means the project is not in the trash or its retention window expired
+func RestoreProject(ctx context.Context, pid int, retention time.Duration) error {
+	tx, err := database.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE deployments d SET deleted_at = NULL FROM projects p
+		WHERE d.project_id = p.id AND p.id = $1 AND d.deleted_at = p.deleted_at`
+	if _, err := tx.ExecContext(ctx, query, pid); err != nil {
+		return fmt.Errorf("failed to restore deployments: %w", err)
+	}
+
+	query = "UPDATE projects SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL AND deleted_at > NOW() - $2 * INTERVAL '1 second'"
+	result, err := tx.ExecContext(ctx, query, pid, retention.Seconds())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to fetch affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// CreateProject returns the id of the new project and whether the subdomain was taken
+func CreateProject(context context.Context, body ProjectBody) (int, bool, error) {
+	var id int
+	query := `INSERT INTO projects(user_id, name, source_code_url, subdomain, custom_domain, source_code, language, is_dockerized,
+		install_command, build_command, output_dir, root_dir, runtime_version) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`
+	err := database.QueryRowContext(context, query, body.UserId, body.Name, body.SourceCodeUrl, body.Subdomain, body.Subdomain, body.SourceCode, body.Language, body.IsDockerized,
+		body.InstallCommand, body.BuildCommand, body.OutputDir, body.RootDir, body.RuntimeVersion).Scan(&id)
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-			return true, errors.New(messages.SubDomainAlreadyExists)
+			return 0, true, errors.New(messages.SubDomainAlreadyExists)
 		}
-		return false, err
+		return 0, false, err
 	}
-	return false, nil
+	return id, false, nil
 }
 
-func UpdateProject(ctx context.Context, id int, name, subDomain string) (bool, error) {
-	query := `UPDATE projects SET name = $1, subdomain = $2 WHERE id = $3;`
-	_, err := database.ExecContext(ctx, query, name, subDomain, id)
+// UpdateProject only changes the fields present in the body, empty or nil values keep the stored ones
+func UpdateProject(ctx context.Context, id int, body UpdateProjectBody) (bool, error) {
+	query := `UPDATE projects SET name = COALESCE(NULLIF($1, ''), name), subdomain = COALESCE(NULLIF($2, ''), subdomain),
+		maintenance_mode = COALESCE($3, maintenance_mode), maintenance_page = COALESCE($4, maintenance_page),
+		retention_keep_ready = COALESCE($5, retention_keep_ready), retention_fail_days = COALESCE($6, retention_fail_days),
+		install_command = COALESCE($7, install_command), build_command = COALESCE($8, build_command),
+		output_dir = COALESCE($9, output_dir), root_dir = COALESCE($10, root_dir), runtime_version = COALESCE($11, runtime_version)
+		WHERE id = $12 AND deleted_at IS NULL;`
+	_, err := database.ExecContext(ctx, query, body.Name, body.Subdomain, body.MaintenanceMode, body.MaintenancePage,
+		body.RetentionKeepReady, body.RetentionFailDays, body.InstallCommand, body.BuildCommand, body.OutputDir, body.RootDir,
+		body.RuntimeVersion, id)
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
 			return true, errors.New(messages.SubDomainAlreadyExists)
@@ -1,5 +1,7 @@
 package project
 
+import "github.com/lib/pq"
+
 type Project struct {
 	Id            int    `json:"id" db:"id"`
 	UserId        string `json:"user_id" db:"user_id"`
@@ -11,6 +13,34 @@ type Project struct {
 	Language      string `json:"language" db:"language"`
 	IsDockerized  string `json:"is_dockerized" db:"is_dockerized"`
 	CreatedAt     string `json:"created_on" db:"created_at"`
+	// Maintenance
+	MaintenanceMode bool   `json:"maintenance_mode" db:"maintenance_mode"`
+	MaintenancePage string `json:"maintenance_page" db:"maintenance_page"`
+	// Build output retention, see infra/artifacts
+	RetentionKeepReady int `json:"retention_keep_ready" db:"retention_keep_ready"`
+	RetentionFailDays  int `json:"retention_fail_days" db:"retention_fail_days"`
+	BuildSettings
+	// DeletedAt is set while the project is in the trash
+	DeletedAt *string `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// TrashedProject is a soft deleted project, it can be restored until PurgeAt
+type TrashedProject struct {
+	Id        int    `json:"id" db:"id"`
+	Name      string `json:"name" db:"name"`
+	Subdomain string `json:"subdomain" db:"subdomain"`
+	DeletedAt string `json:"deleted_at" db:"deleted_at"`
+	PurgeAt   string `json:"purge_at" db:"purge_at"`
+}
+
+// BuildSettings are passed to the builder, empty values keep its defaults
+type BuildSettings struct {
+	InstallCommand string `json:"install_command" db:"install_command"`
+	BuildCommand   string `json:"build_command" db:"build_command"`
+	// OutputDir is relative to RootDir, RootDir to the repository root
+	OutputDir      string `json:"output_dir" db:"output_dir"`
+	RootDir        string `json:"root_dir" db:"root_dir"`
+	RuntimeVersion string `json:"runtime_version" db:"runtime_version"`
 }
 
 type ProjectBody struct {
@@ -22,9 +52,122 @@ type ProjectBody struct {
 	CustomDomain  string `validate:"required" json:"custom_domain"`
 	Language      string `validate:"required" json:"language"`
 	IsDockerized  string `validate:"required" json:"is_dockerized"`
+	BuildSettings
+}
+
+type DetectProjectBody struct {
+	SourceCode    string `validate:"required" json:"source_code"`
+	SourceCodeUrl string `validate:"required" json:"source_code_url"`
+	RootDir       string `json:"root_dir"`
 }
 
 type UpdateProjectBody struct {
-	Name      string `validate:"required" json:"name"`
-	Subdomain string `validate:"required" json:"subdomain"`
+	Name            string  `json:"name"`
+	Subdomain       string  `json:"subdomain"`
+	MaintenanceMode *bool   `json:"maintenance_mode"`
+	MaintenancePage *string `json:"maintenance_page"`
+	// RetentionKeepReady is the number of READY deployments whose outputs are kept, RetentionFailDays
+	// how long FAIL outputs are kept
+	RetentionKeepReady *int `json:"retention_keep_ready" validate:"omitempty,min=1,max=100"`
+	RetentionFailDays  *int `json:"retention_fail_days" validate:"omitempty,min=1,max=365"`
+	// Build settings, an empty string resets the setting to the builder default
+	InstallCommand *string `json:"install_command"`
+	BuildCommand   *string `json:"build_command"`
+	OutputDir      *string `json:"output_dir"`
+	RootDir        *string `json:"root_dir"`
+	RuntimeVersion *string `json:"runtime_version"`
+}
+
+type ProjectAccess struct {
+	ProjectId    int            `json:"project_id" db:"project_id"`
+	AccessMode   string         `json:"access_mode" db:"access_mode"`
+	Username     string         `json:"username" db:"username"`
+	PasswordHash string         `json:"-" db:"password_hash"`
+	AllowedCIDRs pq.StringArray `json:"allowed_cidrs" db:"allowed_cidrs"`
+	CreatedAt    string         `json:"created_on" db:"created_at"`
+	UpdatedAt    string         `json:"updated_at" db:"updated_at"`
+}
+
+type ProjectAccessBody struct {
+	AccessMode   string   `validate:"required,oneof=none basic password" json:"access_mode"`
+	Username     string   `json:"username"`
+	Password     string   `json:"password"`
+	AllowedCIDRs []string `json:"allowed_cidrs"`
+}
+
+type Webhook struct {
+	Id        int            `json:"id" db:"id"`
+	ProjectId int            `json:"project_id" db:"project_id"`
+	URL       string         `json:"url" db:"url"`
+	Secret    string         `json:"-" db:"secret"`
+	Events    pq.StringArray `json:"events" db:"events"`
+	Active    bool           `json:"active" db:"active"`
+	CreatedAt string         `json:"created_on" db:"created_at"`
+	UpdatedAt string         `json:"updated_at" db:"updated_at"`
+}
+
+type WebhookBody struct {
+	URL    string   `validate:"required,url,max=2048" json:"url"`
+	Events []string `validate:"required,min=1" json:"events"`
+	Active *bool    `json:"active"`
+}
+
+type UpdateWebhookBody struct {
+	URL    string   `validate:"omitempty,url,max=2048" json:"url"`
+	Events []string `validate:"omitempty,min=1" json:"events"`
+	Active *bool    `json:"active"`
+	// RotateSecret replaces the signing secret, the new one is returned once in the response
+	RotateSecret bool `json:"rotate_secret"`
+}
+
+type WebhookDelivery struct {
+	Id           int64   `json:"id" db:"id"`
+	WebhookId    int     `json:"webhook_id" db:"webhook_id"`
+	Event        string  `json:"event" db:"event"`
+	Payload      string  `json:"payload" db:"payload"`
+	ResponseCode int     `json:"response_code" db:"response_code"`
+	ResponseBody string  `json:"response_body" db:"response_body"`
+	Error        string  `json:"error" db:"error"`
+	Attempts     int     `json:"attempts" db:"attempts"`
+	DurationMs   int64   `json:"duration_ms" db:"duration_ms"`
+	Success      bool    `json:"success" db:"success"`
+	RedeliveryOf *int64  `json:"redelivery_of" db:"redelivery_of"`
+	CreatedAt    string  `json:"created_on" db:"created_at"`
+	DeliveredAt  *string `json:"delivered_at" db:"delivered_at"`
+}
+
+// NotificationChannel is active only once verified, email channels are verified with a code sent to the target
+type NotificationChannel struct {
+	Id                    int     `json:"id" db:"id"`
+	ProjectId             int     `json:"project_id" db:"project_id"`
+	Type                  string  `json:"type" db:"type"`
+	Target                string  `json:"target" db:"target"`
+	OnSuccess             bool    `json:"on_success" db:"on_success"`
+	OnFailure             bool    `json:"on_failure" db:"on_failure"`
+	Active                bool    `json:"active" db:"active"`
+	VerificationCodeHash  *string `json:"-" db:"verification_code_hash"`
+	VerificationExpiresAt *string `json:"-" db:"verification_expires_at"`
+	VerifiedAt            *string `json:"verified_at" db:"verified_at"`
+	CreatedAt             string  `json:"created_on" db:"created_at"`
+	UpdatedAt             string  `json:"updated_at" db:"updated_at"`
+}
+
+// NotificationChannelBody targets a webhook url for chat channels and an address for email channels
+type NotificationChannelBody struct {
+	Type      string `validate:"required,oneof=slack discord chat email" json:"type"`
+	Target    string `validate:"required,max=2048" json:"target"`
+	OnSuccess *bool  `json:"on_success"`
+	OnFailure *bool  `json:"on_failure"`
+	Active    *bool  `json:"active"`
+}
+
+type VerifyNotificationChannelBody struct {
+	Code string `validate:"required,max=64" json:"code"`
+}
+
+type UpdateNotificationChannelBody struct {
+	Target    string `validate:"omitempty,max=2048" json:"target"`
+	OnSuccess *bool  `json:"on_success"`
+	OnFailure *bool  `json:"on_failure"`
+	Active    *bool  `json:"active"`
 }
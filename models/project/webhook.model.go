@@ -0,0 +1,90 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/webhook"
+)
+
+var webhookDispatcher = webhook.NewDispatcher(database)
+
+func CreateWebhook(ctx context.Context, pid int, url, secret string, events []string, active bool) (Webhook, error) {
+	var model Webhook
+	query := `INSERT INTO webhooks(project_id, url, secret, events, active) VALUES($1, $2, $3, $4, $5) RETURNING *`
+	err := database.GetContext(ctx, &model, query, pid, url, secret, pq.Array(events), active)
+	if err != nil {
+		return model, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return model, nil
+}
+
+func GetWebhooks(ctx context.Context, pid int) ([]Webhook, error) {
+	webhooks := make([]Webhook, 0)
+	query := "SELECT * FROM webhooks WHERE project_id = $1 ORDER BY id"
+	err := database.SelectContext(ctx, &webhooks, query, pid)
+	return webhooks, err
+}
+
+func GetWebhookById(ctx context.Context, pid, wid int) (Webhook, error) {
+	var model Webhook
+	query := "SELECT * FROM webhooks WHERE id = $1 AND project_id = $2"
+	err := database.GetContext(ctx, &model, query, wid, pid)
+	return model, err
+}
+
+func UpdateWebhook(ctx context.Context, hook Webhook) error {
+	query := `UPDATE webhooks SET url = $2, secret = $3, events = $4, active = $5, updated_at = NOW() WHERE id = $1`
+	_, err := database.ExecContext(ctx, query, hook.Id, hook.URL, hook.Secret, pq.Array(hook.Events), hook.Active)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
+func DeleteWebhook(ctx context.Context, pid, wid int) error {
+	query := "DELETE FROM webhooks WHERE id = $1 AND project_id = $2"
+
+	result, err := database.ExecContext(ctx, query, wid, pid)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to fetch affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return errors.New("webhook not found or already deleted")
+	}
+
+	return nil
+}
+
+func GetWebhookDeliveries(ctx context.Context, wid, itemsPerPage, offset int) ([]WebhookDelivery, error) {
+	deliveries := make([]WebhookDelivery, 0)
+	query := "SELECT * FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3"
+	err := database.SelectContext(ctx, &deliveries, query, wid, itemsPerPage, offset)
+	return deliveries, err
+}
+
+func GetTotalWebhookDeliveriesCount(ctx context.Context, wid int) (int, error) {
+	var total int
+	query := "SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1"
+	err := database.GetContext(ctx, &total, query, wid)
+	return total, err
+}
+
+func GetWebhookDeliveryById(ctx context.Context, wid int, did int64) (WebhookDelivery, error) {
+	var model WebhookDelivery
+	query := "SELECT * FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2"
+	err := database.GetContext(ctx, &model, query, did, wid)
+	return model, err
+}
+
+// RedeliverWebhook resends a past delivery, waiting for its retries to finish
+func RedeliverWebhook(ctx context.Context, did int64) (webhook.Delivery, error) {
+	return webhookDispatcher.Redeliver(ctx, did)
+}
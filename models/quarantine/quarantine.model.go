@@ -0,0 +1,54 @@
+package quarantine
+
+import (
+	"context"
+
+	"github.com/swarajkumarsingh/turbo-deploy/infra/db"
+)
+
+var database = db.Mgr.DBConn
+
+const StatusQuarantined = "QUARANTINED"
+const StatusRedriven = "REDRIVEN"
+
+// listCondition filters by status and source queue, empty values match everything
+const listCondition = `($1 = '' OR status::text = $1) AND ($2 = '' OR source_queue = $2)`
+
+func GetQuarantinedMessages(ctx context.Context, status, sourceQueue string, itemsPerPage, offset int) ([]QuarantinedMessage, error) {
+	messages := make([]QuarantinedMessage, 0)
+	query := `SELECT * FROM quarantined_messages WHERE ` + listCondition + ` ORDER BY id DESC LIMIT $3 OFFSET $4`
+	err := database.SelectContext(ctx, &messages, query, status, sourceQueue, itemsPerPage, offset)
+	return messages, err
+}
+
+func GetTotalQuarantinedMessagesCount(ctx context.Context, status, sourceQueue string) (int, error) {
+	var total int
+	query := `SELECT COUNT(*) FROM quarantined_messages WHERE ` + listCondition
+	err := database.GetContext(ctx, &total, query, status, sourceQueue)
+	return total, err
+}
+
+func GetQuarantinedMessageById(ctx context.Context, id int64) (QuarantinedMessage, error) {
+	var message QuarantinedMessage
+	query := `SELECT * FROM quarantined_messages WHERE id = $1`
+	err := database.GetContext(ctx, &message, query, id)
+	return message, err
+}
+
+// MarkRedriven claims a quarantined message for redrive, it returns false when it was already redriven
+func MarkRedriven(ctx context.Context, id int64) (bool, error) {
+	query := `UPDATE quarantined_messages SET status = 'REDRIVEN', redriven_at = NOW() WHERE id = $1 AND status = 'QUARANTINED'`
+	result, err := database.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// UnmarkRedriven releases the claim taken by MarkRedriven when the message could not be sent
+func UnmarkRedriven(ctx context.Context, id int64) error {
+	query := `UPDATE quarantined_messages SET status = 'QUARANTINED', redriven_at = NULL WHERE id = $1`
+	_, err := database.ExecContext(ctx, query, id)
+	return err
+}
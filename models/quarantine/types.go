@@ -0,0 +1,14 @@
+package quarantine
+
+type QuarantinedMessage struct {
+	Id             int64   `json:"id" db:"id"`
+	SourceQueue    string  `json:"source_queue" db:"source_queue"`
+	MessageId      string  `json:"message_id" db:"message_id"`
+	MessageGroupId string  `json:"message_group_id" db:"message_group_id"`
+	Body           string  `json:"body" db:"body"`
+	Error          string  `json:"error" db:"error"`
+	ReceiveCount   int     `json:"receive_count" db:"receive_count"`
+	Status         string  `json:"status" db:"status"`
+	RedrivenAt     *string `json:"redriven_at" db:"redriven_at"`
+	CreatedAt      string  `json:"created_at" db:"created_at"`
+}
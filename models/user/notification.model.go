@@ -0,0 +1,34 @@
+package userModel
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetNotificationPreferences returns the user's preferences, every notification is enabled until the user opts out
+func GetNotificationPreferences(ctx context.Context, uid int) (NotificationPreferences, error) {
+	var model NotificationPreferences
+	query := `SELECT COALESCE(np.deployment_success, TRUE) AS deployment_success,
+			COALESCE(np.deployment_failure, TRUE) AS deployment_failure,
+			COALESCE(np.deployment_cancelled, TRUE) AS deployment_cancelled,
+			COALESCE(np.quota_warning, TRUE) AS quota_warning
+		FROM users u LEFT JOIN notification_preferences np ON np.user_id = u.id
+		WHERE u.id = $1`
+	err := database.GetContext(ctx, &model, query, uid)
+	if err == nil {
+		return model, nil
+	}
+	return model, err
+}
+
+func UpsertNotificationPreferences(ctx context.Context, uid int, prefs NotificationPreferences) error {
+	query := `INSERT INTO notification_preferences(user_id, deployment_success, deployment_failure, deployment_cancelled, quota_warning)
+		VALUES($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET deployment_success = $2, deployment_failure = $3, deployment_cancelled = $4,
+			quota_warning = $5, updated_at = NOW()`
+	_, err := database.ExecContext(ctx, query, uid, prefs.DeploymentSuccess, prefs.DeploymentFailure, prefs.DeploymentCancelled, prefs.QuotaWarning)
+	if err != nil {
+		return fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+	return nil
+}
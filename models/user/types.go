@@ -11,7 +11,6 @@ type User struct {
 	Password    string `json:"password" db:"password"`
 	Phone       string `json:"phone" db:"phone"`
 	IsActive    string `json:"is_active" db:"is_active"`
-	IsDeleted   string `json:"is_deleted" db:"is_deleted"`
 	Address     string `json:"address" db:"address"`
 	Location    string `json:"location" db:"location"`
 	Experience  string `json:"experience" db:"experience"`
@@ -20,6 +19,8 @@ type User struct {
 	PlanType    string `json:"plan_type" db:"plan_type"`
 	CreatedAt   string `json:"created_on" db:"created_at"`
 	UpdatedAt   string `json:"updated_at" db:"updated_at"`
+	// DeletedAt is set once the account was deleted, the purge job removes it after the retention window
+	DeletedAt *string `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
 type UserBody struct {
@@ -51,3 +52,18 @@ type Claims struct {
 	UserId string `json:"userId"`
 	jwt.RegisteredClaims
 }
+
+type NotificationPreferences struct {
+	DeploymentSuccess   bool `json:"deployment_success" db:"deployment_success"`
+	DeploymentFailure   bool `json:"deployment_failure" db:"deployment_failure"`
+	DeploymentCancelled bool `json:"deployment_cancelled" db:"deployment_cancelled"`
+	QuotaWarning        bool `json:"quota_warning" db:"quota_warning"`
+}
+
+// NotificationPreferencesBody leaves a preference unchanged when its field is omitted
+type NotificationPreferencesBody struct {
+	DeploymentSuccess   *bool `json:"deployment_success"`
+	DeploymentFailure   *bool `json:"deployment_failure"`
+	DeploymentCancelled *bool `json:"deployment_cancelled"`
+	QuotaWarning        *bool `json:"quota_warning"`
+}
@@ -59,7 +59,7 @@ func GetUserByUsernameWithUserId(context context.Context, userId string) (User,
 		return userModel, errors.New("invalid username")
 	}
 
-	query := "SELECT * FROM users WHERE id = $1"
+	query := "SELECT * FROM users WHERE id = $1 AND deleted_at IS NULL"
 	err := database.GetContext(context, &userModel, query, userId)
 	if err == nil {
 		return userModel, nil
@@ -81,7 +81,7 @@ func CheckIfUsernameExistsWithId(context context.Context, userId string) (User,
 }
 
 func UpdateUser(context context.Context, uid int, body UserUpdateBody) error {
-	query := "UPDATE users SET username = $2, firstname = $3, lastname = $4, address = $5, experience = $6, primary_goal = $7, user_role = $8, plan_type = $9 WHERE id = $1"
+	query := "UPDATE users SET username = $2, firstname = $3, lastname = $4, address = $5, experience = $6, primary_goal = $7, user_role = $8, plan_type = $9 WHERE id = $1 AND deleted_at IS NULL"
 	res, err := database.ExecContext(context, query, uid, body.Username, body.FirstName, body.LastName, body.Address, body.Experience, body.PrimaryGoal, body.UserRole, body.PlanType)
 	if err != nil {
 		return err
@@ -95,10 +95,17 @@ func UpdateUser(context context.Context, uid int, body UserUpdateBody) error {
 	return nil
 }
 
+// DeleteUser soft deletes the user along with their projects and deployments, all stamped with the same
+// deleted_at so the purge job removes them together once the retention window expired
 func DeleteUser(ctx context.Context, uid int) error {
-	query := "DELETE FROM users WHERE id = $1"
+	tx, err := database.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	result, err := database.ExecContext(ctx, query, uid)
+	query := "UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL"
+	result, err := tx.ExecContext(ctx, query, uid)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -111,7 +118,14 @@ func DeleteUser(ctx context.Context, uid int) error {
 		return errors.New("user not found or user already deleted")
 	}
 
-	return nil
+	if _, err := tx.ExecContext(ctx, "UPDATE projects SET deleted_at = NOW() WHERE user_id = $1 AND deleted_at IS NULL", uid); err != nil {
+		return fmt.Errorf("failed to delete projects: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE deployments SET deleted_at = NOW() WHERE user_id = $1 AND deleted_at IS NULL", uid); err != nil {
+		return fmt.Errorf("failed to delete deployments: %w", err)
+	}
+
+	return tx.Commit()
 }
 
 func GetUserByUsername(context context.Context, username string) (User, error) {
@@ -121,7 +135,7 @@ func GetUserByUsername(context context.Context, username string) (User, error) {
 		return userModel, errors.New("invalid username")
 	}
 
-	query := "SELECT * FROM users WHERE username = $1"
+	query := "SELECT * FROM users WHERE username = $1 AND deleted_at IS NULL"
 	err := database.GetContext(context, &userModel, query, username)
 	if err == nil {
 		return userModel, nil
@@ -131,7 +145,7 @@ func GetUserByUsername(context context.Context, username string) (User, error) {
 
 func GetUserById(context context.Context, uid int) (User, error) {
 	var userModel User
-	query := "SELECT * FROM users WHERE id = $1"
+	query := "SELECT * FROM users WHERE id = $1 AND deleted_at IS NULL"
 	err := database.GetContext(context, &userModel, query, uid)
 	if err == nil {
 		return userModel, nil
@@ -146,7 +160,7 @@ func GetUserIdByUsername(context context.Context, username string) (int, error)
 		return 0, errors.New("invalid username")
 	}
 
-	query := "SELECT id FROM users WHERE username = $1"
+	query := "SELECT id FROM users WHERE username = $1 AND deleted_at IS NULL"
 	err := database.GetContext(context, &userId, query, username)
 	if err == nil {
 		return userId, nil
@@ -0,0 +1,13 @@
+package auditRoutes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/authentication"
+	"github.com/swarajkumarsingh/turbo-deploy/controller/audit"
+)
+
+func AddRoutes(router *gin.Engine) {
+	r := router.Group("/")
+
+	r.GET("/audit", authentication.AuthorizeAdmin, audit.GetAuditEvents)
+}
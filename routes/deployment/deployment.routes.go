@@ -10,8 +10,11 @@ func AddRoutes(router *gin.Engine) {
 	r := router.Group("/")
 
 	r.POST("/deployment", deployment.CreateDeployment)
+	r.POST("/project/:pid/deployments/upload", authentication.AuthorizeUser, deployment.UploadDeployment)
 	r.GET("/deployment/:id", deployment.GetDeployment)
 	r.GET("/deployment/:id/status", deployment.GetDeploymentStatus)
+	r.POST("/deployment/:id/redeploy", authentication.AuthorizeUser, deployment.RedeployDeployment)
+	r.POST("/deployment/:id/promote", authentication.AuthorizeUser, deployment.PromoteDeployment)
 	r.GET("/deployment", authentication.AuthorizeUser, deployment.GetAllDeployment)
 	r.DELETE("/deployment/:id", deployment.DeleteDeployment)
 	r.DELETE("/deployment", authentication.AuthorizeUser, deployment.DeleteAllDeployment)
@@ -2,6 +2,7 @@ package deploymentLogRoutes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/authentication"
 	"github.com/swarajkumarsingh/turbo-deploy/controller/deployment_log"
 )
 
@@ -9,4 +10,6 @@ func AddRoutes(router *gin.Engine) {
 	r := router.Group("/")
 	
 	r.GET("/deployment/:id/logs", deployment_logs.GetDeploymentLogs)
+	r.GET("/deployment/:id/logs/search", authentication.AuthorizeUser, deployment_logs.SearchDeploymentLogs)
+	r.GET("/project/:pid/logs/search", authentication.AuthorizeUser, deployment_logs.SearchProjectLogs)
 }
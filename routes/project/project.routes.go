@@ -10,9 +10,33 @@ func AddRoutes(router *gin.Engine) {
 	r := router.Group("/")
 	
 	r.POST("/project", project.CreateProject)
+	r.POST("/project/detect", authentication.AuthorizeUser, project.DetectFramework)
 	r.GET("/project/:pid", project.GetProject)
 	r.GET("/projects", authentication.AuthorizeUser, project.GetAllProject)
-	r.PATCH("/project/:pid", project.UpdateProject)
+	r.PATCH("/project/:pid", authentication.AuthorizeUser, project.UpdateProject)
 	r.DELETE("/project/:pid", project.DeleteProject)
 	r.DELETE("/project/", authentication.AuthorizeUser, project.DeleteAllProject)
+	r.GET("/projects/trash", authentication.AuthorizeUser, project.GetTrashedProjects)
+	r.POST("/project/:pid/restore", authentication.AuthorizeUser, project.RestoreProject)
+
+	r.GET("/project/:pid/access", authentication.AuthorizeUser, project.GetProjectAccess)
+	r.PUT("/project/:pid/access", authentication.AuthorizeUser, project.UpdateProjectAccess)
+	r.DELETE("/project/:pid/access", authentication.AuthorizeUser, project.DeleteProjectAccess)
+
+	r.GET("/project/:pid/analytics", authentication.AuthorizeUser, project.GetProjectAnalytics)
+
+	r.POST("/project/:pid/webhooks", authentication.AuthorizeUser, project.CreateWebhook)
+	r.GET("/project/:pid/webhooks", authentication.AuthorizeUser, project.GetWebhooks)
+	r.GET("/project/:pid/webhooks/:wid", authentication.AuthorizeUser, project.GetWebhook)
+	r.PATCH("/project/:pid/webhooks/:wid", authentication.AuthorizeUser, project.UpdateWebhook)
+	r.DELETE("/project/:pid/webhooks/:wid", authentication.AuthorizeUser, project.DeleteWebhook)
+	r.GET("/project/:pid/webhooks/:wid/deliveries", authentication.AuthorizeUser, project.GetWebhookDeliveries)
+	r.POST("/project/:pid/webhooks/:wid/deliveries/:did/redeliver", authentication.AuthorizeUser, project.RedeliverWebhook)
+
+	r.POST("/project/:pid/channels", authentication.AuthorizeUser, project.CreateNotificationChannel)
+	r.GET("/project/:pid/channels", authentication.AuthorizeUser, project.GetNotificationChannels)
+	r.PATCH("/project/:pid/channels/:cid", authentication.AuthorizeUser, project.UpdateNotificationChannel)
+	r.DELETE("/project/:pid/channels/:cid", authentication.AuthorizeUser, project.DeleteNotificationChannel)
+	r.POST("/project/:pid/channels/:cid/verify", authentication.AuthorizeUser, project.VerifyNotificationChannel)
+	r.POST("/project/:pid/channels/:cid/verification", authentication.AuthorizeUser, project.ResendChannelVerification)
 }
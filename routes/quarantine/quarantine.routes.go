@@ -0,0 +1,15 @@
+package quarantineRoutes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/authentication"
+	"github.com/swarajkumarsingh/turbo-deploy/controller/quarantine"
+)
+
+func AddRoutes(router *gin.Engine) {
+	r := router.Group("/admin", authentication.AuthorizeAdmin)
+
+	r.GET("/quarantine", quarantine.GetQuarantinedMessages)
+	r.GET("/quarantine/:id", quarantine.GetQuarantinedMessage)
+	r.POST("/quarantine/:id/redrive", quarantine.RedriveQuarantinedMessage)
+}
@@ -2,6 +2,7 @@ package userRoutes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/swarajkumarsingh/turbo-deploy/authentication"
 	"github.com/swarajkumarsingh/turbo-deploy/controller/user"
 )
 
@@ -12,4 +13,7 @@ func AddRoutes(router *gin.Engine) {
 	r.GET("/user/:uid", user.GetUser)
 	r.PATCH("/user/:uid", user.UpdateUser)
 	r.DELETE("/user/:uid", user.DeleteUser)
+
+	r.GET("/user/notifications", authentication.AuthorizeUser, user.GetNotificationPreferences)
+	r.PUT("/user/notifications", authentication.AuthorizeUser, user.UpdateNotificationPreferences)
 }
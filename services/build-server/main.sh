@@ -1,7 +1,35 @@
 #!/bin/bash
+set -o pipefail
 
 export GIT_REPOSITORY_URL="$GIT_REPOSITORY_URL"
 
-git clone "$GIT_REPOSITORY_URL" /home/app/output
+# fail reports the error as a FAIL status and log through script.js and stops the build
+fail() {
+  echo "$1" >&2
+  SOURCE_ERROR="$1" node script.js
+  exit 1
+}
+
+# Uploaded deployments bring their source as an archive instead of a repository
+if [ -n "$SOURCE_ARCHIVE_URL" ]; then
+  mkdir -p /home/app/output
+  curl -sSfL "$SOURCE_ARCHIVE_URL" | tar -xz -C /home/app/output || fail "Could not download the source archive"
+else
+  # GIT_BRANCH and COMMIT_SHA pin the build, redeploys set both to rebuild the exact same commit
+  clone_args=()
+  if [ -n "$GIT_BRANCH" ]; then
+    clone_args+=(--branch "$GIT_BRANCH")
+  fi
+  git clone "${clone_args[@]}" "$GIT_REPOSITORY_URL" /home/app/output || fail "Could not clone $GIT_REPOSITORY_URL"
+  if [ -n "$COMMIT_SHA" ]; then
+    git -C /home/app/output checkout --quiet "$COMMIT_SHA" || fail "Could not check out commit $COMMIT_SHA"
+  fi
+fi
+
+# RUNTIME_VERSION selects the node major version, the image version is used when it is empty
+if [ -n "$RUNTIME_VERSION" ]; then
+  n "$RUNTIME_VERSION" || echo "Could not install node $RUNTIME_VERSION, using $(node --version)"
+  hash -r
+fi
 
 exec node script.js
\ No newline at end of file
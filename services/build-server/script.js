@@ -2,9 +2,11 @@ import os from "os";
 import fs from "fs";
 import http from "http";
 import path from "path";
+import zlib from "zlib";
+import { promisify } from "util";
 import { dirname } from "path";
 import { fileURLToPath } from "url";
-import { spawn } from "child_process";
+import { spawn, execFileSync } from "child_process";
 import { VULNERABLE_COMMANDS } from "./vulnerableCommands.js";
 
 import https from "https";
@@ -37,13 +39,21 @@ const {
   ENVIRONMENT,
   DEPLOYMENT_ID,
   LOG_QUEUE_URL,
+  SQS_ENDPOINT,
   S3_BUCKET_NAME,
   BUILD_TEST_URL,
   EMAIL_QUEUE_URL,
-  RECIPIENT_EMAIL,
   STATUS_QUEUE_URL,
   AWS_ACCESS_KEY_ID,
   AWS_SECRET_ACCESS_KEY,
+  INSTALL_COMMAND,
+  BUILD_COMMAND,
+  OUTPUT_DIR,
+  ROOT_DIR,
+  GIT_BRANCH,
+  COMMIT_SHA,
+  CLEAR_BUILD_CACHE,
+  SOURCE_ERROR,
 } = process.env;
 
 const s3Client = new S3Client({
@@ -54,38 +64,53 @@ const s3Client = new S3Client({
   },
 });
 
+// SQS_ENDPOINT points the queues at a local emulator such as ElasticMQ or LocalStack
 const sqsClient = new SQSClient({
   region: AWS_REGION,
   credentials: {
     accessKeyId: AWS_ACCESS_KEY_ID,
     secretAccessKey: AWS_SECRET_ACCESS_KEY,
   },
+  ...(SQS_ENDPOINT && { endpoint: SQS_ENDPOINT }),
 });
 
 const MAX_RETRIES = 3;
 const MIN_RETRY_TIMEOUT = 1000;
 const MAX_RETRY_TIMEOUT = 5000;
 
-const APPLICATION_STATUS_SUBJECT =
-  "Application Deployment Status | Turbo-Deploy";
-const SUCCESS_DEPLOYMENT_BODY = `Congrats! Your application with Project id: ${PROJECT_ID} & Deployment id: ${DEPLOYMENT_ID} is deployed successfully. Please Visit Turbo Deploy for more details`;
+// Emails are rendered by the email consumer from the event type
+const EmailEvent = {
+  SUCCESS: "deployment_success",
+  FAILURE: "deployment_failure",
+  CANCELLED: "deployment_cancelled",
+};
 
 const DEFAULT_BUILD_FOLDER = "build";
 const DEFAULT_OUTPUT_FOLDER = "output";
+const MAX_COMMIT_MESSAGE_LENGTH = 4096;
 const outputFolders = ["dist", "build", "public", "release"];
 
+// Project build settings override these, see BuildSettings in the API
+const DEFAULT_INSTALL_COMMAND = "npm install";
+const DEFAULT_BUILD_COMMAND = "npm run build";
+
 const __filename = fileURLToPath(import.meta.url);
 const __dirname = dirname(__filename);
 
 const MAX_FILE_SIZE = 1024 * 1024;
-const PACKAGE_JSON_PATH = path.join(
-  __dirname,
-  DEFAULT_OUTPUT_FOLDER,
-  "package.json"
-);
 
 const queue = new PQueue({ concurrency: 5 });
 
+const gzip = promisify(zlib.gzip);
+const brotliCompress = promisify(zlib.brotliCompress);
+
+// Files smaller than this are not worth compressing
+const MIN_COMPRESS_SIZE = 1024;
+const COMPRESSIBLE_EXTENSIONS = [
+  ".html", ".htm", ".css", ".js", ".mjs", ".json",
+  ".map", ".svg", ".txt", ".xml", ".wasm", ".ico",
+];
+
 const requiredEnvVars = [
   "APP_NAME",
   "AWS_REGION",
@@ -94,7 +119,6 @@ const requiredEnvVars = [
   "LOG_QUEUE_URL",
   "DEPLOYMENT_ID",
   "S3_BUCKET_NAME",
-  "RECIPIENT_EMAIL",
   "EMAIL_QUEUE_URL",
   "STATUS_QUEUE_URL",
   "AWS_ACCESS_KEY_ID",
@@ -168,23 +192,65 @@ async function pushDeploymentStatus(status) {
     status: sanitizedStatus,
     host,
     timestamp: new Date().toISOString(),
+    commitSha: checkout.commitSha,
+    branch: checkout.branch,
+    commitMessage: checkout.commitMessage,
+    commitAuthor: checkout.commitAuthor,
   };
   await publishToQueue(STATUS_QUEUE_URL, statusMessage);
   console.log(`Status: ${status}`);
 }
 
-async function pushEmailQueue(recipient_email, subject, body) {
+// checkout is the commit that was cloned, its fields are empty for uploaded sources
+const checkout = readCheckout(path.join(__dirname, DEFAULT_OUTPUT_FOLDER));
+
+function readCheckout(repositoryPath) {
+  if (!fs.existsSync(path.join(repositoryPath, ".git"))) {
+    return { commitSha: "", branch: "", commitMessage: "", commitAuthor: "" };
+  }
+  const git = (...args) => {
+    try {
+      return execFileSync("git", ["-C", repositoryPath, ...args], {
+        encoding: "utf8",
+      }).trim();
+    } catch (err) {
+      return "";
+    }
+  };
+  // a checked out commit leaves HEAD detached, the requested branch is reported then
+  const branch = git("rev-parse", "--abbrev-ref", "HEAD");
+  return {
+    commitSha: git("rev-parse", "HEAD"),
+    branch: branch && branch !== "HEAD" ? branch : GIT_BRANCH || "",
+    commitMessage: git("log", "-1", "--format=%B").slice(0, MAX_COMMIT_MESSAGE_LENGTH),
+    commitAuthor: git("log", "-1", "--format=%an"),
+  };
+}
+
+// clearBuildCache removes dependency and framework caches that came with the source
+function clearBuildCache(projectPath) {
+  for (const cacheDir of ["node_modules", ".cache", path.join(".next", "cache")]) {
+    fs.rmSync(path.join(projectPath, cacheDir), { recursive: true, force: true });
+  }
+}
+
+async function pushEmailQueue(event, { url = "", error = "" } = {}) {
   const emailMessage = {
     appName: APP_NAME,
-    recipient_email: recipient_email || RECIPIENT_EMAIL,
-    subject: subject || APPLICATION_STATUS_SUBJECT,
-    body: body || SUCCESS_DEPLOYMENT_BODY,
+    event,
+    url,
+    error: stripAnsi(error),
     projectId: PROJECT_ID,
     deploymentId: DEPLOYMENT_ID,
     timestamp: new Date().toISOString(),
   };
   await publishToQueue(EMAIL_QUEUE_URL, emailMessage);
-  console.log(`Email Status: Success`);
+  console.log(`Email Status: ${event}`);
+}
+
+async function failDeployment(reason) {
+  await pushDeploymentStatus(DeploymentStatus.FAIL);
+  await pushEmailQueue(EmailEvent.FAILURE, { error: reason });
 }
 
 function sanitizePath(dirPath) {
@@ -232,12 +298,25 @@ const getAllFiles = (dirPath, files = []) => {
   return files;
 };
 
-function checkValidBuildCommandFromPackageFile() {
+// Install and build commands are package manager steps joined by &&, the API validates them the same way
+const COMMAND_STEP_PATTERN = /^(npm|npx|yarn|pnpm)( +[A-Za-z0-9@._:=/+-]+)*$/;
+
+function isValidCustomCommand(command) {
+  return command
+    .split("&&")
+    .every((step) => COMMAND_STEP_PATTERN.test(step.trim()));
+}
+
+// A custom build command does not need a package.json build script, but one that exists is still checked
+function checkValidBuildCommandFromPackageFile(projectDir, required = true) {
+  const packageJsonPath = path.join(projectDir, "package.json");
+  if (!required && !fs.existsSync(packageJsonPath)) return true;
+
   try {
-    const stats = fs.statSync(PACKAGE_JSON_PATH);
+    const stats = fs.statSync(packageJsonPath);
     if (stats.size > MAX_FILE_SIZE) return false;
 
-    const data = fs.readFileSync(PACKAGE_JSON_PATH, {
+    const data = fs.readFileSync(packageJsonPath, {
       encoding: "utf-8",
       flag: "r",
     });
@@ -254,7 +333,7 @@ function checkValidBuildCommandFromPackageFile() {
       !packageJson.scripts.build ||
       typeof packageJson.scripts.build !== "string"
     )
-      return false;
+      return !required;
 
     const buildCommand = packageJson.scripts.build;
     const sanitizedCommand = buildCommand.trim().replace(/^["']|["']$/g, "");
@@ -267,82 +346,149 @@ function checkValidBuildCommandFromPackageFile() {
   }
 }
 
-function getDeploymentSuccessHtml(projectId, deploymentId) {
-  return `
-    <!DOCTYPE html>
-    <html lang="en">
-    <head>
-        <meta charset="UTF-8">
-        <meta name="viewport" content="width=device-width, initial-scale=1.0">
-        <title>Application Deployment Status | Turbo-Deploy</title>
-        <style>
-            body {
-                font-family: Arial, sans-serif;
-                margin: 0;
-                padding: 0;
-                background-color: #f9f9f9;
-                color: #333;
-                line-height: 1.6;
-            }
-            .container {
-                max-width: 600px;
-                margin: 20px auto;
-                background: #ffffff;
-                border-radius: 8px;
-                box-shadow: 0 2px 4px rgba(0, 0, 0, 0.1);
-                padding: 20px;
-                border: 1px solid #ddd;
-            }
-            h1 {
-                color: #4CAF50;
-                text-align: center;
-                font-size: 24px;
-            }
-            p {
-                margin: 10px 0;
-            }
-            .details {
-                background: #f1f1f1;
-                border: 1px solid #ddd;
-                padding: 10px;
-                border-radius: 6px;
-                margin: 20px 0;
-            }
-            a {
-                color: #4CAF50;
-                text-decoration: none;
-            }
-            a:hover {
-                text-decoration: underline;
-            }
-            .footer {
-                text-align: center;
-                margin-top: 20px;
-                font-size: 12px;
-                color: #666;
-            }
-        </style>
-    </head>
-    <body>
-        <div class="container">
-            <h1>Application Deployment Status</h1>
-            <p>Dear User,</p>
-            <p>Congratulations! Your application has been successfully deployed. Below are the deployment details:</p>
-            <div class="details">
-                <p><strong>Project ID:</strong> ${projectId}</p>
-                <p><strong>Deployment ID:</strong> ${deploymentId}</p>
-            </div>
-            <p>You can now access your deployed application and explore its features. For further details, please visit 
-                <a href="#" target="_blank">Turbo Deploy</a>.
-            </p>
-            <p>Thank you for choosing Turbo-Deploy for your application deployment needs. We look forward to supporting your future projects.</p>
-            <div class="footer">
-                <p>&copy; 2024 Turbo-Deploy. All rights reserved.</p>
-            </div>
-        </div>
-    </body>
-    </html>
-    `;
+async function uploadWithRetry(command, relativeFilePath) {
+  await retry(
+    async () => {
+      await s3Client.send(command);
+    },
+    {
+      retries: MAX_RETRIES,
+      factor: 2,
+      minTimeout: MIN_RETRY_TIMEOUT,
+      maxTimeout: MAX_RETRY_TIMEOUT,
+      onRetry: async (error, attempt) => {
+        console.log(
+          `Retry attempt ${attempt} failed for ${relativeFilePath}: ${error.message}`
+        );
+        await publishLog({
+          message: `Retry attempt ${attempt} failed for ${relativeFilePath}: ${error.message}`,
+          logType: LogType.ERROR,
+        });
+      },
+    }
+  );
+}
+
+// Stores .br and .gz variants next to the original so the proxy can serve them by Accept-Encoding
+async function uploadCompressedVariants(filePath, s3Key, relativeFilePath) {
+  if (!COMPRESSIBLE_EXTENSIONS.includes(path.extname(filePath).toLowerCase())) {
+    return;
+  }
+
+  const content = await fs.promises.readFile(filePath);
+  if (content.length < MIN_COMPRESS_SIZE) {
+    return;
+  }
+
+  const variants = [
+    {
+      encoding: "br",
+      extension: ".br",
+      body: await brotliCompress(content, {
+        params: { [zlib.constants.BROTLI_PARAM_QUALITY]: 11 },
+      }),
+    },
+    {
+      encoding: "gzip",
+      extension: ".gz",
+      body: await gzip(content, { level: 9 }),
+    },
+  ];
+
+  for (const variant of variants) {
+    if (variant.body.length >= content.length) continue;
+
+    const command = new PutObjectCommand({
+      Bucket: S3_BUCKET_NAME,
+      Key: `${s3Key}${variant.extension}`,
+      Body: variant.body,
+      ContentType: mime.lookup(filePath) || "application/octet-stream",
+      ContentEncoding: variant.encoding,
+    });
+    await uploadWithRetry(command, `${relativeFilePath}${variant.extension}`);
+  }
+}
+
+// Files and folders starting with a dot, like .git, are not served unless they are under .well-known
+function isHiddenPath(relativePath) {
+  return relativePath
+    .split(path.sep)
+    .some((segment) => segment.startsWith(".") && segment !== ".well-known");
+}
+
+// deployOutput uploads distFolderPath, marks the deployment READY and exits
+async function deployOutput(distFolderPath, outputFolder, skipHidden = false) {
+  if (!fs.existsSync(distFolderPath)) {
+    throw new Error(`Output folder "${outputFolder}" does not exist.`);
+  }
+
+  const filesToUpload = getAllFiles(distFolderPath).filter(
+    (filePath) =>
+      !skipHidden || !isHiddenPath(path.relative(distFolderPath, filePath))
+  );
+  if (filesToUpload.length === 0) {
+    throw new Error(
+      `No files found in the output folder: ${distFolderPath}`
+    );
+  }
+
+  await publishLog({
+    message: `Starting to upload in dir ${outputFolder}`,
+  });
+
+  for (const filePath of filesToUpload) {
+    queue.add(async () => {
+      const relativeFilePath = path.relative(distFolderPath, filePath);
+      const s3Key = `__outputs/${DEPLOYMENT_ID}/${relativeFilePath.replace(
+        /\\/g,
+        "/"
+      )}`;
+
+      await publishLog({ message: `Uploading ${relativeFilePath}` });
+
+      const command = new PutObjectCommand({
+        Bucket: S3_BUCKET_NAME,
+        Key: s3Key,
+        Body: fs.createReadStream(filePath),
+        ContentType: mime.lookup(filePath) || "application/octet-stream",
+      });
+
+      try {
+        await uploadWithRetry(command, relativeFilePath);
+        await uploadCompressedVariants(filePath, s3Key, relativeFilePath);
+        await publishLog({ message: `Uploaded ${relativeFilePath}` });
+      } catch (uploadError) {
+        console.error(
+          `Failed to upload file ${relativeFilePath}: ${uploadError.message}`
+        );
+        await publishLog({
+          message: `Failed to upload file ${relativeFilePath}: ${uploadError.message}`,
+          logType: LogType.ERROR,
+        });
+        throw new Error(
+          "Failed to upload file ${relativeFilePath}: ${uploadError.message}"
+        );
+      }
+    });
+  }
+
+  await queue.onIdle();
+
+  await publishLog({ message: "All files uploaded successfully." });
+
+  const ok = isStatusCode200(BUILD_TEST_URL);
+  if (!ok) {
+    await publishLog({
+      message: `Project deployed but in undefined state. Try again later. URL: ${BUILD_TEST_URL}`,
+      logType: LogType.WARN,
+    });
+  }
+
+  await publishLog({ message: "Deployment testing completed :)" });
+  await pushDeploymentStatus(DeploymentStatus.READY);
+  await pushEmailQueue(EmailEvent.SUCCESS, { url: BUILD_TEST_URL });
+  await publishLog({ message: "Done..." });
+  process.exit(0);
 }
 
 async function init() {
@@ -352,20 +498,76 @@ async function init() {
     await publishLog({ message: "Build Started..." });
     await pushDeploymentStatus(DeploymentStatus.PROG);
 
+    // main.sh sets SOURCE_ERROR when the source could not be fetched, only the failure is reported then
+    if (SOURCE_ERROR) {
+      await publishLog({ message: SOURCE_ERROR, logType: LogType.ERROR });
+      await failDeployment(SOURCE_ERROR);
+      process.exit(1);
+    }
+
+    if (COMMIT_SHA && checkout.commitSha !== COMMIT_SHA) {
+      await publishLog({
+        message: `Commit ${COMMIT_SHA} could not be checked out`,
+        logType: LogType.ERROR,
+      });
+      await failDeployment(`Commit ${COMMIT_SHA} could not be checked out`);
+      process.exit(1);
+    }
+    if (checkout.commitSha) {
+      await publishLog({
+        message: `Building commit ${checkout.commitSha}${checkout.branch ? ` on ${checkout.branch}` : ""}`,
+      });
+    }
+
+    // ROOT_DIR points at the project inside a monorepo
     const outDirPath = sanitizePath(
-      path.join(__dirname, DEFAULT_OUTPUT_FOLDER)
+      path.join(__dirname, DEFAULT_OUTPUT_FOLDER, ROOT_DIR || "")
     );
+    if (!fs.existsSync(outDirPath)) {
+      throw new Error(`Root directory "${ROOT_DIR}" does not exist.`);
+    }
 
-    const validBuildCommand = checkValidBuildCommandFromPackageFile(outDirPath);
+    // Plain html sites without build settings or a package.json are deployed as they are
+    const isStaticSite =
+      !INSTALL_COMMAND &&
+      !BUILD_COMMAND &&
+      !fs.existsSync(path.join(outDirPath, "package.json")) &&
+      fs.existsSync(path.join(outDirPath, OUTPUT_DIR || "", "index.html"));
+    if (isStaticSite) {
+      await publishLog({
+        message: "No package.json found, deploying static files",
+      });
+      await deployOutput(
+        sanitizePath(path.join(outDirPath, OUTPUT_DIR || "")),
+        OUTPUT_DIR || ".",
+        true
+      );
+      return;
+    }
+
+    const installCommand = INSTALL_COMMAND || DEFAULT_INSTALL_COMMAND;
+    const buildCommand = BUILD_COMMAND || DEFAULT_BUILD_COMMAND;
+
+    const validBuildCommand =
+      isValidCustomCommand(installCommand) &&
+      isValidCustomCommand(buildCommand) &&
+      checkValidBuildCommandFromPackageFile(outDirPath, !BUILD_COMMAND);
     if (!validBuildCommand) {
       await publishLog({ message: "Vulnerable build command found :(" });
-      await pushDeploymentStatus(DeploymentStatus.FAIL);
+      await failDeployment("Vulnerable build command found");
       process.exit(1);
     }
 
-    await publishLog({ message: "Executing npm install & npm build commands" });
+    if (CLEAR_BUILD_CACHE === "true") {
+      await publishLog({ message: "Clearing build cache" });
+      clearBuildCache(outDirPath);
+    }
 
-    const p = spawn("npm", ["install", "&&", "npm", "run", "build"], {
+    await publishLog({
+      message: `Executing ${installCommand} & ${buildCommand} on node ${process.version}`,
+    });
+
+    const p = spawn(`${installCommand} && ${buildCommand}`, {
       cwd: outDirPath,
       shell: true,
     });
@@ -389,7 +591,7 @@ async function init() {
         name: error.name,
         stack: error.stack.toString(),
       });
-      await pushDeploymentStatus(DeploymentStatus.FAIL);
+      await failDeployment(`Error starting process: ${error}`);
       process.exit(1);
     });
 
@@ -399,117 +601,26 @@ async function init() {
           message: `Build process exited with code ${code}`,
           logType: LogType.ERROR,
         });
-        await pushDeploymentStatus(DeploymentStatus.FAIL);
+        await failDeployment(`Build process exited with code ${code}`);
         process.exit(1);
       }
     });
 
     p.on("close", async function () {
       await publishLog({
-        message: "npm install & npm build completed successfully",
+        message: `${installCommand} & ${buildCommand} completed successfully`,
       });
 
       const outputFolder =
+        OUTPUT_DIR ||
         outputFolders.find((folder) =>
-          fs.existsSync(path.join(__dirname, DEFAULT_OUTPUT_FOLDER, folder))
-        ) || DEFAULT_BUILD_FOLDER;
-
-      const distFolderPath = path.join(
-        __dirname,
-        DEFAULT_OUTPUT_FOLDER,
-        outputFolder
-      );
-
-      if (!fs.existsSync(distFolderPath)) {
-        throw new Error(`Output folder "${outputFolder}" does not exist.`);
-      }
-
-      const filesToUpload = getAllFiles(distFolderPath);
-      if (filesToUpload.length === 0) {
-        throw new Error(
-          `No files found in the output folder: ${distFolderPath}`
-        );
-      }
+          fs.existsSync(path.join(outDirPath, folder))
+        ) ||
+        DEFAULT_BUILD_FOLDER;
 
-      await publishLog({
-        message: `Starting to upload in dir ${outputFolder}`,
-      });
-
-      for (const filePath of filesToUpload) {
-        queue.add(async () => {
-          const relativeFilePath = path.relative(distFolderPath, filePath);
-          const s3Key = `__outputs/${DEPLOYMENT_ID}/${relativeFilePath.replace(
-            /\\/g,
-            "/"
-          )}`;
-
-          await publishLog({ message: `Uploading ${relativeFilePath}` });
-
-          const command = new PutObjectCommand({
-            Bucket: S3_BUCKET_NAME,
-            Key: s3Key,
-            Body: fs.createReadStream(filePath),
-            ContentType: mime.lookup(filePath) || "application/octet-stream",
-          });
-
-          try {
-            await retry(
-              async () => {
-                await s3Client.send(command);
-                await publishLog({ message: `Uploaded ${relativeFilePath}` });
-              },
-              {
-                retries: MAX_RETRIES,
-                factor: 2,
-                minTimeout: MIN_RETRY_TIMEOUT,
-                maxTimeout: MAX_RETRY_TIMEOUT,
-                onRetry: async (error, attempt) => {
-                  console.log(
-                    `Retry attempt ${attempt} failed for ${relativeFilePath}: ${error.message}`
-                  );
-                  await publishLog({
-                    message: `Retry attempt ${attempt} failed for ${relativeFilePath}: ${error.message}`,
-                    logType: LogType.ERROR,
-                  });
-                },
-              }
-            );
-          } catch (uploadError) {
-            console.error(
-              `Failed to upload file ${relativeFilePath}: ${uploadError.message}`
-            );
-            await publishLog({
-              message: `Failed to upload file ${relativeFilePath}: ${uploadError.message}`,
-              logType: LogType.ERROR,
-            });
-            throw new Error(
-              "Failed to upload file ${relativeFilePath}: ${uploadError.message}"
-            );
-          }
-        });
-      }
-
-      await queue.onIdle();
-
-      await publishLog({ message: "All files uploaded successfully." });
+      const distFolderPath = sanitizePath(path.join(outDirPath, outputFolder));
 
-      const ok = isStatusCode200(BUILD_TEST_URL);
-      if (!ok) {
-        await publishLog({
-          message: `Project deployed but in undefined state. Try again later. URL: ${BUILD_TEST_URL}`,
-          logType: LogType.WARN,
-        });
-      }
-
-      await publishLog({ message: "Deployment testing completed :)" });
-      await pushDeploymentStatus(DeploymentStatus.READY);
-      await pushEmailQueue(
-        RECIPIENT_EMAIL,
-        APPLICATION_STATUS_SUBJECT,
-        getDeploymentSuccessHtml(PROJECT_ID, DEPLOYMENT_ID),
-      );
-      await publishLog({ message: "Done..." });
-      process.exit(0);
+      await deployOutput(distFolderPath, outputFolder);
     });
   } catch (error) {
     console.error("Deployment failed:", error);
@@ -518,13 +629,14 @@ async function init() {
       logType: LogType.ERROR,
     });
 
-    await pushDeploymentStatus(DeploymentStatus.FAIL);
+    await failDeployment(`Deployment failed: ${error.message}`);
     process.exit(1);
   }
 }
 
 process.on("SIGTERM", async () => {
   console.log("SIGTERM received, shutting down gracefully");
+  await pushEmailQueue(EmailEvent.CANCELLED);
   process.exit(0);
 });
 
@@ -535,7 +647,7 @@ process.on("unhandledRejection", async (reason, promise) => {
     message: `Unhandled Rejection: ${reason}`,
     logType: LogType.ERROR,
   });
-  await pushDeploymentStatus(DeploymentStatus.FAIL);
+  await failDeployment(`Unhandled Rejection: ${reason}`);
 
   process.exit(1);
 });
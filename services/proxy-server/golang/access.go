@@ -0,0 +1,240 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	accessModeNone     = "none"
+	accessModeBasic    = "basic"
+	accessModePassword = "password"
+
+	accessCookieName = "__turbo_access"
+	accessLoginPath  = "/__turbo/login"
+	accessCookieTTL  = 24 * time.Hour
+)
+
+// accessConfig is the access policy of a single project
+type accessConfig struct {
+	ProjectId    int
+	Mode         string
+	Username     string
+	PasswordHash string
+	AllowedNets  []*net.IPNet
+}
+
+// accessGuard enforces project access policies and signs the password form cookies
+type accessGuard struct {
+	cookieSecret      []byte
+	trustForwardedFor bool
+}
+
+var loginTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Protected Site | Turbo-Deploy</title>
+    <style>
+        body { font-family: Arial, sans-serif; background: #f9f9f9; color: #333; display: flex; align-items: center; justify-content: center; height: 100vh; margin: 0; }
+        form { background: #fff; border: 1px solid #ddd; border-radius: 8px; padding: 24px; width: 320px; box-shadow: 0 2px 4px rgba(0, 0, 0, 0.1); }
+        input[type=password] { width: 100%; padding: 8px; margin: 12px 0; box-sizing: border-box; }
+        button { width: 100%; padding: 8px; background: #4CAF50; color: #fff; border: none; border-radius: 4px; cursor: pointer; }
+        .error { color: #c0392b; font-size: 14px; }
+    </style>
+</head>
+<body>
+    <form method="POST" action="{{.Action}}">
+        <h2>This site is password protected</h2>
+        {{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+        <input type="password" name="password" placeholder="Password" autofocus required>
+        <input type="hidden" name="redirect" value="{{.Redirect}}">
+        <button type="submit">Continue</button>
+    </form>
+</body>
+</html>`))
+
+func newAccessGuard() *accessGuard {
+	secret := []byte(os.Getenv("ACCESS_COOKIE_SECRET"))
+	if len(secret) == 0 {
+		log.Println("ACCESS_COOKIE_SECRET not set, using a random secret; access cookies will not survive restarts")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("Unable to generate cookie secret: %v", err)
+		}
+	}
+
+	trustForwardedFor, _ := strconv.ParseBool(os.Getenv("TRUST_FORWARDED_FOR"))
+
+	return &accessGuard{
+		cookieSecret:      secret,
+		trustForwardedFor: trustForwardedFor,
+	}
+}
+
+// authorize enforces the access policy for the request, writing the response and returning false when it is denied
+func (g *accessGuard) authorize(w http.ResponseWriter, r *http.Request, config *accessConfig) bool {
+	if config == nil {
+		return true
+	}
+
+	if len(config.AllowedNets) > 0 && !ipAllowed(g.clientIP(r), config.AllowedNets) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+
+	switch config.Mode {
+	case accessModeBasic:
+		return g.authorizeBasic(w, r, config)
+	case accessModePassword:
+		return g.authorizePassword(w, r, config)
+	default:
+		return true
+	}
+}
+
+func (g *accessGuard) authorizeBasic(w http.ResponseWriter, r *http.Request, config *accessConfig) bool {
+	username, password, ok := r.BasicAuth()
+	if ok && subtle.ConstantTimeCompare([]byte(username), []byte(config.Username)) == 1 &&
+		bcrypt.CompareHashAndPassword([]byte(config.PasswordHash), []byte(password)) == nil {
+		return true
+	}
+
+	w.Header().Set("WWW-Authenticate", `Basic realm="Protected Site", charset="UTF-8"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	return false
+}
+
+func (g *accessGuard) authorizePassword(w http.ResponseWriter, r *http.Request, config *accessConfig) bool {
+	if cookie, err := r.Cookie(accessCookieName); err == nil && g.validCookie(cookie.Value, config) {
+		return true
+	}
+
+	if r.Method != http.MethodPost || r.URL.Path != accessLoginPath {
+		renderLogin(w, r.URL.RequestURI(), "")
+		return false
+	}
+
+	redirect := safeRedirect(r.PostFormValue("redirect"))
+	if bcrypt.CompareHashAndPassword([]byte(config.PasswordHash), []byte(r.PostFormValue("password"))) != nil {
+		renderLogin(w, redirect, "Incorrect password, please try again.")
+		return false
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     accessCookieName,
+		Value:    g.signCookie(config, time.Now().Add(accessCookieTTL)),
+		Path:     "/",
+		MaxAge:   int(accessCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+	return false
+}
+
+// signCookie binds the cookie to the project and its current password hash so rotating the password revokes it
+func (g *accessGuard) signCookie(config *accessConfig, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%d.%d", config.ProjectId, expiresAt.Unix())
+	return payload + "." + g.mac(payload, config.PasswordHash)
+}
+
+func (g *accessGuard) validCookie(value string, config *accessConfig) bool {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 || parts[0] != strconv.Itoa(config.ProjectId) {
+		return false
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+
+	expected := g.mac(parts[0]+"."+parts[1], config.PasswordHash)
+	return hmac.Equal([]byte(parts[2]), []byte(expected))
+}
+
+func (g *accessGuard) mac(payload, passwordHash string) string {
+	h := hmac.New(sha256.New, g.cookieSecret)
+	h.Write([]byte(payload))
+	h.Write([]byte(passwordHash))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func (g *accessGuard) clientIP(r *http.Request) net.IP {
+	if g.trustForwardedFor {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return net.ParseIP(strings.TrimSpace(strings.Split(forwarded, ",")[0]))
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+func ipAllowed(ip net.IP, networks []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// safeRedirect only allows relative paths on the same host
+func safeRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+	return target
+}
+
+func renderLogin(w http.ResponseWriter, redirect, errorMessage string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusUnauthorized)
+
+	err := loginTemplate.Execute(w, map[string]string{
+		"Action":   accessLoginPath,
+		"Redirect": safeRedirect(redirect),
+		"Error":    errorMessage,
+	})
+	if err != nil {
+		log.Printf("Error rendering login page: %v", err)
+	}
+}
+
+// stripAccessCredentials removes proxy credentials so they are never forwarded to the origin bucket
+func stripAccessCredentials(r *http.Request) {
+	r.Header.Del("Authorization")
+
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if cookie.Name != accessCookieName {
+			r.AddCookie(cookie)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	accessLogBufferSize    = 10000
+	accessLogBatchSize     = 500
+	accessLogFlushInterval = 2 * time.Second
+	accessLogColumns       = 10
+	accessLogBatchTimeout  = 10 * time.Second
+	accessLogRowTimeout    = time.Second
+	maxLoggedFieldLength   = 1024
+	// sizes of the VARCHAR columns of access_logs, in characters
+	maxHostLength   = 255
+	maxMethodLength = 10
+)
+
+// accessLogEntry is a single served request of a project
+type accessLogEntry struct {
+	ProjectId int
+	Host      string
+	Path      string
+	Method    string
+	Status    int
+	Bytes     int64
+	LatencyMs int
+	UserAgent string
+	Referer   string
+	CreatedAt time.Time
+}
+
+// accessLogSink buffers access log entries and writes them to Postgres in batches
+type accessLogSink struct {
+	db      *sql.DB
+	entries chan accessLogEntry
+	done    chan struct{}
+	once    sync.Once
+}
+
+// newAccessLogSink starts the batch writer
+func newAccessLogSink(db *sql.DB) *accessLogSink {
+	sink := &accessLogSink{
+		db:      db,
+		entries: make(chan accessLogEntry, accessLogBufferSize),
+		done:    make(chan struct{}),
+	}
+	go sink.run()
+	return sink
+}
+
+// record queues the entry without blocking, entries are dropped when the buffer is full
+func (s *accessLogSink) record(entry accessLogEntry) {
+	if s == nil {
+		return
+	}
+
+	select {
+	case s.entries <- entry.sanitized():
+	default:
+		log.Printf("Access log buffer full, dropping entry for project %d", entry.ProjectId)
+	}
+}
+
+// close flushes the buffered entries and stops the writer
+func (s *accessLogSink) close(ctx context.Context) {
+	if s == nil {
+		return
+	}
+
+	s.once.Do(func() { close(s.entries) })
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		log.Printf("Access log flush interrupted: %v", ctx.Err())
+	}
+}
+
+func (s *accessLogSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(accessLogFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]accessLogEntry, 0, accessLogBatchSize)
+	for {
+		select {
+		case entry, ok := <-s.entries:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= accessLogBatchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (s *accessLogSink) flush(batch []accessLogEntry) {
+	if len(batch) == 0 {
+		return
+	}
+
+	placeholders := make([]string, 0, len(batch))
+	args := make([]interface{}, 0, len(batch)*accessLogColumns)
+	for i, entry := range batch {
+		base := i * accessLogColumns
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10))
+		args = append(args, entry.values()...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), accessLogBatchTimeout)
+	_, err := s.db.ExecContext(ctx, insertAccessLogsQuery+strings.Join(placeholders, ", "), args...)
+	cancel()
+	if err == nil {
+		return
+	}
+	log.Printf("Failed to write %d access log entries, retrying one by one: %v", len(batch), err)
+
+	// one bad row fails the whole insert, so the others are written on their own. Every row gets its own
+	// timeout, a batch that timed out would leave none for them otherwise
+	failed := 0
+	for _, entry := range batch {
+		if err := s.insert(entry); err != nil {
+			failed++
+			log.Printf("Failed to write access log entry for project %d: %v", entry.ProjectId, err)
+		}
+	}
+	if failed > 0 {
+		log.Printf("Dropped %d of %d access log entries", failed, len(batch))
+	}
+}
+
+func (s *accessLogSink) insert(entry accessLogEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), accessLogRowTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, insertAccessLogsQuery+"($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)", entry.values()...)
+	return err
+}
+
+const insertAccessLogsQuery = `INSERT INTO access_logs (project_id, host, path, method, status, bytes, latency_ms, user_agent, referer, created_at)
+	VALUES `
+
+func (e accessLogEntry) values() []interface{} {
+	return []interface{}{e.ProjectId, e.Host, e.Path, e.Method, e.Status, e.Bytes, e.LatencyMs, e.UserAgent, e.Referer, e.CreatedAt}
+}
+
+// sanitized fits the client controlled fields into their columns
+func (e accessLogEntry) sanitized() accessLogEntry {
+	e.Host = sanitizeField(e.Host, maxHostLength)
+	e.Path = sanitizeField(e.Path, maxLoggedFieldLength)
+	e.Method = sanitizeField(e.Method, maxMethodLength)
+	e.UserAgent = sanitizeField(e.UserAgent, maxLoggedFieldLength)
+	e.Referer = sanitizeField(e.Referer, maxLoggedFieldLength)
+	return e
+}
+
+// responseRecorder captures the status code and body size written to the client
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+func (rec *responseRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// sanitizeField drops invalid UTF-8 and NUL bytes, which Postgres rejects in text columns, and cuts value
+// to at most max characters
+func sanitizeField(value string, max int) string {
+	value = strings.ReplaceAll(strings.ToValidUTF8(value, ""), "\x00", "")
+	if utf8.RuneCountInString(value) <= max {
+		return value
+	}
+
+	count := 0
+	for i := range value {
+		if count == max {
+			return value[:i]
+		}
+		count++
+	}
+	return value
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	variantCacheTTL       = 5 * time.Minute
+	variantCacheSize      = 10000
+	immutableCacheHeader  = "public, max-age=31536000, immutable"
+	revalidateCacheHeader = "public, max-age=0, must-revalidate"
+)
+
+// contentEncodings lists the precompressed variants uploaded by the build server, in order of preference
+var contentEncodings = []struct {
+	name      string
+	extension string
+}{
+	{name: "br", extension: ".br"},
+	{name: "gzip", extension: ".gz"},
+}
+
+var compressibleExtensions = map[string]bool{
+	".html": true, ".htm": true, ".css": true, ".js": true, ".mjs": true, ".json": true,
+	".map": true, ".svg": true, ".txt": true, ".xml": true, ".wasm": true, ".ico": true,
+}
+
+// hexHashRegex matches hex fingerprints such as main.8f3a2b1c.css or chunk-5e1f0c9a77d2.js
+var hexHashRegex = regexp.MustCompile(`[.-]([0-9a-f]{8,})\.[a-zA-Z0-9]+$`)
+
+// base64HashRegex matches base64url fingerprints such as index-BkX3a9_c.js, which are only trusted inside
+// the output directories of known bundlers since names like my-component1.js have the same shape
+var base64HashRegex = regexp.MustCompile(`[.-]([a-zA-Z0-9_-]{8,})\.[a-zA-Z0-9]+$`)
+
+// hashedAssetDirs are where bundlers write fingerprinted files, vite and astro emit /assets and /_astro,
+// next /_next/static and create-react-app /static/{js,css,media}
+var hashedAssetDirs = []string{"/assets/", "/_astro/", "/_next/static/", "/static/js/", "/static/css/", "/static/media/"}
+
+type variantCacheEntry struct {
+	key       string
+	exists    bool
+	expiresAt time.Time
+}
+
+// variantCache remembers which precompressed objects exist so S3 is only asked once per asset. It holds at most
+// variantCacheSize entries and evicts the least recently used one, every distinct path would grow it otherwise
+type variantCache struct {
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newVariantCache() *variantCache {
+	return &variantCache{order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *variantCache) get(key string) (exists, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return false, false
+	}
+	entry := element.Value.(*variantCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return false, false
+	}
+	c.order.MoveToFront(element)
+	return entry.exists, true
+}
+
+func (c *variantCache) set(key string, exists bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &variantCacheEntry{key: key, exists: exists, expiresAt: time.Now().Add(variantCacheTTL)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > variantCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*variantCacheEntry).key)
+	}
+}
+
+func (rp *ReverseProxy) variantExists(ctx context.Context, key string) bool {
+	if exists, ok := rp.variants.get(key); ok {
+		return exists
+	}
+
+	_, err := rp.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(rp.bucketName),
+		Key:    aws.String(key),
+	})
+
+	rp.variants.set(key, err == nil)
+	return err == nil
+}
+
+// negotiateEncoding rewrites the request to a precompressed variant accepted by the client and returns its encoding
+func (rp *ReverseProxy) negotiateEncoding(r *http.Request, prefix string) string {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return ""
+	}
+	if !isCompressible(r.URL.Path) || r.Header.Get("Range") != "" {
+		return ""
+	}
+
+	accepted := parseAcceptEncoding(r.Header.Get("Accept-Encoding"))
+	for _, encoding := range contentEncodings {
+		if !accepted[encoding.name] {
+			continue
+		}
+		if rp.variantExists(r.Context(), prefix+r.URL.Path+encoding.extension) {
+			r.URL.Path += encoding.extension
+			r.URL.RawPath = ""
+			return encoding.name
+		}
+	}
+	return ""
+}
+
+// parseAcceptEncoding returns the codings the client accepts, ignoring those with q=0. A wildcard only accepts
+// the codings that were not listed on their own, so "gzip;q=0, *" still refuses gzip
+func parseAcceptEncoding(header string) map[string]bool {
+	accepted := make(map[string]bool)
+	listed := make(map[string]bool)
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		if coding == "*" {
+			wildcard = quality > 0
+			continue
+		}
+		listed[coding] = true
+		if quality > 0 {
+			accepted[coding] = true
+		}
+	}
+
+	if wildcard {
+		for _, encoding := range contentEncodings {
+			if !listed[encoding.name] {
+				accepted[encoding.name] = true
+			}
+		}
+	}
+	return accepted
+}
+
+// setAssetHeaders fixes up encoding and caching headers of a response for the originally requested asset
+func setAssetHeaders(resp *http.Response, assetPath, encoding string) {
+	if isCompressible(assetPath) {
+		resp.Header.Add("Vary", "Accept-Encoding")
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified {
+		return
+	}
+
+	if encoding != "" {
+		resp.Header.Set("Content-Encoding", encoding)
+		if contentType := mime.TypeByExtension(path.Ext(assetPath)); contentType != "" {
+			resp.Header.Set("Content-Type", contentType)
+		}
+	}
+
+	switch {
+	case isHashedAsset(assetPath):
+		resp.Header.Set("Cache-Control", immutableCacheHeader)
+	case strings.HasSuffix(assetPath, ".html"):
+		resp.Header.Set("Cache-Control", revalidateCacheHeader)
+	}
+}
+
+func isCompressible(assetPath string) bool {
+	return compressibleExtensions[strings.ToLower(path.Ext(assetPath))]
+}
+
+// isHashedAsset reports whether the file name carries a content hash. Hex hashes must mix letters and
+// digits, base64url hashes are only accepted under hashedAssetDirs
+func isHashedAsset(assetPath string) bool {
+	name := path.Base(assetPath)
+	if match := hexHashRegex.FindStringSubmatch(name); match != nil && isMixedHash(match[1]) {
+		return true
+	}
+
+	for _, dir := range hashedAssetDirs {
+		if strings.HasPrefix(assetPath, dir) {
+			match := base64HashRegex.FindStringSubmatch(name)
+			return match != nil && isMixedHash(match[1])
+		}
+	}
+	return false
+}
+
+// isMixedHash rejects all letter or all digit candidates such as words and dates
+func isMixedHash(hash string) bool {
+	return strings.ContainsAny(hash, "0123456789") && strings.IndexFunc(hash, unicode.IsLetter) >= 0
+}
@@ -6,7 +6,9 @@ require (
 	github.com/aws/aws-sdk-go-v2 v1.32.6
 	github.com/aws/aws-sdk-go-v2/config v1.28.6
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.71.0
+	github.com/lib/pq v1.10.9
 	github.com/rs/cors v1.11.1
+	golang.org/x/crypto v0.27.0
 	golang.org/x/time v0.8.0
 )
 
@@ -9,13 +9,11 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
-	"regexp"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/rs/cors"
@@ -36,6 +34,10 @@ type ReverseProxy struct {
 	limiter    *rate.Limiter
 	s3Client   *s3.Client
 	bucketName string
+	routes     *routingTable
+	access     *accessGuard
+	variants   *variantCache
+	accessLogs *accessLogSink
 }
 
 func NewReverseProxy() *ReverseProxy {
@@ -48,63 +50,79 @@ func NewReverseProxy() *ReverseProxy {
 	// Create S3 client
 	s3Client := s3.NewFromConfig(cfg)
 
+	routes := newRoutingTable(os.Getenv("DB_URL"))
+
 	return &ReverseProxy{
 		limiter:    rate.NewLimiter(rate.Limit(100), 200),
 		s3Client:   s3Client,
 		bucketName: baseBucketPath,
+		routes:     routes,
+		access:     newAccessGuard(),
+		variants:   newVariantCache(),
+		accessLogs: newAccessLogSink(routes.db),
 	}
 }
 
-func (rp *ReverseProxy) validateAndGetDeployment(subdomain string) (string, error) {
-	// Validate subdomain format
-	validSubdomainRegex := regexp.MustCompile(`^[a-zA-Z0-9-_]{1,63}$`)
-	if !validSubdomainRegex.MatchString(subdomain) {
-		return "", fmt.Errorf("invalid subdomain format")
-	}
-
-	// Construct the full path to check
-	deploymentPath := fmt.Sprintf("__outputs/%s/index.html", subdomain)
-
-	// Check if the deployment exists in S3
-	_, err := rp.s3Client.HeadObject(context.TODO(), &s3.HeadObjectInput{
-		Bucket: aws.String(rp.bucketName),
-		Key:    aws.String(deploymentPath),
-	})
-
-	if err != nil {
-		log.Printf("Deployment not found for subdomain %s: %v", subdomain, err)
-		return "", fmt.Errorf("deployment not found")
-	}
-
+// deploymentURL returns the S3 location of the build output of a deployment
+func (rp *ReverseProxy) deploymentURL(deploymentId int) string {
 	// Use the correct S3 endpoint for the region
-	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/__outputs/%s", 
-		rp.bucketName, region, subdomain), nil
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s",
+		rp.bucketName, region, deploymentPrefix(deploymentId))
+}
+
+func deploymentPrefix(deploymentId int) string {
+	return fmt.Sprintf("__outputs/%d", deploymentId)
 }
 
 func (rp *ReverseProxy) handleProxy(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestPath := r.URL.Path
+
 	// Rate limiting
 	if err := rp.limiter.Wait(r.Context()); err != nil {
 		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
 		return
 	}
 
-	// Extract subdomain
+	// Resolve the project from the in-memory routing table, unknown hosts never reach S3
 	hostname := r.Host
-	parts := strings.Split(hostname, ".")
+	project := rp.routes.lookup(hostname)
+	if project == nil {
+		renderStatusPage(w, r, stateNotFound, "")
+		return
+	}
+
+	// Record the request once the response has been written
+	recorder := newResponseRecorder(w)
+	w = recorder
+	defer func() {
+		rp.accessLogs.record(accessLogEntry{
+			ProjectId: project.ProjectId,
+			Host:      hostname,
+			Path:      requestPath,
+			Method:    r.Method,
+			Status:    recorder.status,
+			Bytes:     recorder.bytes,
+			LatencyMs: int(time.Since(start).Milliseconds()),
+			UserAgent: r.UserAgent(),
+			Referer:   r.Referer(),
+			CreatedAt: start,
+		})
+	}()
 
-	// Handle localhost and other development environments
-	var subdomain string
-	if len(parts) > 1 {
-		subdomain = parts[0]
+	// Enforce project access controls before any content is served
+	if !rp.access.authorize(w, r, project.Access) {
+		return
 	}
 
-	// Validate and get deployment URL
-	targetURLStr, err := rp.validateAndGetDeployment(subdomain)
-	if err != nil {
-		log.Printf("Deployment validation failed: %v", err)
-		http.Error(w, "Invalid or Not Found Deployment", http.StatusNotFound)
+	// Maintenance, building and failed deployments get a status page
+	if state := unavailableState(project); state != "" {
+		renderStatusPage(w, r, state, maintenancePage(project, state))
 		return
 	}
+	stripAccessCredentials(r)
+
+	targetURLStr := rp.deploymentURL(project.DeploymentId)
 
 	// Parse target URL
 	targetURL, err := url.Parse(targetURLStr)
@@ -119,13 +137,23 @@ func (rp *ReverseProxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 	if originalPath == "/" {
 		r.URL.Path = "/index.html"
 	}
+	assetPath := r.URL.Path
+
+	// Serve a precompressed variant when the client accepts one
+	encoding := rp.negotiateEncoding(r, deploymentPrefix(project.DeploymentId))
 
 	// Create reverse proxy
 	proxy := httputil.NewSingleHostReverseProxy(targetURL)
 
+	// Content negotiation and caching headers
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		setAssetHeaders(resp, assetPath, encoding)
+		return nil
+	}
+
 	// Advanced error handling
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		log.Printf("Proxy error for subdomain %s: %v", subdomain, err)
+		log.Printf("Proxy error for project %d: %v", project.ProjectId, err)
 
 		switch {
 		case strings.Contains(err.Error(), "PermanentRedirect"):
@@ -140,8 +168,8 @@ func (rp *ReverseProxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Log proxy details for debugging
-	log.Printf("Proxying request: Subdomain=%s, Original Path=%s, Target URL=%s",
-		subdomain, originalPath, targetURLStr)
+	log.Printf("Proxying request: Host=%s, Deployment=%d, Original Path=%s, Target URL=%s",
+		hostname, project.DeploymentId, originalPath, targetURLStr)
 
 	// Serve the proxy request
 	proxy.ServeHTTP(w, r)
@@ -159,6 +187,10 @@ func main() {
 	// Create reverse proxy handler
 	rp := NewReverseProxy()
 
+	// Keep the routing table in sync with project and deployment changes
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	go rp.routes.listen(listenCtx)
+
 	// Configure CORS
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
@@ -201,5 +233,10 @@ func main() {
 		log.Fatalf("Server Shutdown Failed: %v", err)
 	}
 
+	stopListening()
+
+	// Write the remaining access logs
+	rp.accessLogs.close(ctx)
+
 	log.Println("Server stopped gracefully")
 }
\ No newline at end of file
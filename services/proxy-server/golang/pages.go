@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// siteState is the reason a site cannot be served
+type siteState string
+
+const (
+	stateMaintenance siteState = "maintenance"
+	stateNotFound    siteState = "not_found"
+	stateBuilding    siteState = "building"
+	stateFailed      siteState = "failed"
+)
+
+type statusPage struct {
+	Code    int
+	Title   string
+	Message string
+}
+
+var statusPages = map[siteState]statusPage{
+	stateMaintenance: {
+		Code:    http.StatusServiceUnavailable,
+		Title:   "Under Maintenance",
+		Message: "This site is undergoing scheduled maintenance. Please check back soon.",
+	},
+	stateNotFound: {
+		Code:    http.StatusNotFound,
+		Title:   "Deployment Not Found",
+		Message: "There is no deployment at this address. Check the URL or deploy your project from Turbo Deploy.",
+	},
+	stateBuilding: {
+		Code:    http.StatusServiceUnavailable,
+		Title:   "Deployment In Progress",
+		Message: "This site is being built right now. It will be available as soon as the build finishes.",
+	},
+	stateFailed: {
+		Code:    http.StatusNotFound,
+		Title:   "Deployment Failed",
+		Message: "The latest build of this site failed. Check the deployment logs on Turbo Deploy for details.",
+	},
+}
+
+var statusTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}} | Turbo-Deploy</title>
+    <style>
+        body { font-family: Arial, sans-serif; background: #f9f9f9; color: #333; display: flex; align-items: center; justify-content: center; height: 100vh; margin: 0; }
+        .container { max-width: 480px; background: #fff; border: 1px solid #ddd; border-radius: 8px; padding: 24px; text-align: center; box-shadow: 0 2px 4px rgba(0, 0, 0, 0.1); }
+        h1 { color: #4CAF50; font-size: 24px; }
+        .code { color: #999; font-size: 14px; }
+        .footer { margin-top: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>{{.Title}}</h1>
+        <p>{{.Message}}</p>
+        <p class="code">{{.Code}}</p>
+        <div class="footer">Powered by Turbo-Deploy</div>
+    </div>
+</body>
+</html>`))
+
+// renderStatusPage writes the branded page for state, or its JSON variant for API clients.
+// customHTML replaces the default page when the project configured one.
+func renderStatusPage(w http.ResponseWriter, r *http.Request, state siteState, customHTML string) {
+	page := statusPages[state]
+
+	w.Header().Set("Cache-Control", "no-store")
+	if state == stateBuilding || state == stateMaintenance {
+		w.Header().Set("Retry-After", "30")
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(page.Code)
+		err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   true,
+			"status":  state,
+			"message": page.Message,
+		})
+		if err != nil {
+			log.Printf("Error writing status response: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(page.Code)
+
+	if customHTML != "" {
+		if _, err := w.Write([]byte(customHTML)); err != nil {
+			log.Printf("Error writing custom status page: %v", err)
+		}
+		return
+	}
+
+	if err := statusTemplate.Execute(w, page); err != nil {
+		log.Printf("Error rendering status page: %v", err)
+	}
+}
+
+// wantsJSON reports whether the client prefers JSON over HTML, based on the first media type it accepts
+func wantsJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch {
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			return true
+		case mediaType == "text/html":
+			return false
+		}
+	}
+	return false
+}
+
+// unavailableState returns why the project cannot be served, empty when it can
+func unavailableState(config *projectConfig) siteState {
+	if config.MaintenanceMode {
+		return stateMaintenance
+	}
+	if config.DeploymentId != 0 {
+		return ""
+	}
+
+	switch config.LatestStatus {
+	case "QUEUE", "PROG":
+		return stateBuilding
+	case "FAIL":
+		return stateFailed
+	default:
+		return stateNotFound
+	}
+}
+
+// maintenancePage returns the custom html configured for the maintenance state
+func maintenancePage(config *projectConfig, state siteState) string {
+	if state != stateMaintenance {
+		return ""
+	}
+	return config.MaintenancePage
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	routesChannel        = "proxy_routes"
+	routesResyncInterval = 5 * time.Minute
+	routesQueryTimeout   = 30 * time.Second
+	listenerMinReconnect = 1 * time.Second
+	listenerMaxReconnect = 30 * time.Second
+	listenerPingInterval = 90 * time.Second
+)
+
+// projectConfig holds the per-project settings the proxy needs before serving content
+type projectConfig struct {
+	ProjectId       int
+	Subdomain       string
+	CustomDomain    string
+	MaintenanceMode bool
+	MaintenancePage string
+	// DeploymentId is the latest successful or promoted production deployment served for the project, zero when there is none
+	DeploymentId int
+	// LatestStatus is the status of the most recent deployment, empty when the project was never deployed
+	LatestStatus string
+	// Access is nil when the project has no access controls
+	Access *accessConfig
+}
+
+// routingTable maps hostnames to projects, it is loaded at startup and kept up to date via Postgres notifications
+type routingTable struct {
+	db          *sql.DB
+	databaseURL string
+	mu          sync.RWMutex
+	projects    map[int]*projectConfig
+	hosts       map[string]*projectConfig
+}
+
+const routesQuery = `SELECT p.id, COALESCE(p.subdomain, ''), COALESCE(p.custom_domain, ''), p.maintenance_mode, p.maintenance_page,
+		COALESCE((SELECT d.status::text FROM deployments d WHERE d.project_id = p.id AND d.deleted_at IS NULL AND d.target = 'production' ORDER BY d.id DESC LIMIT 1), ''),
+		COALESCE((SELECT d.id FROM deployments d WHERE d.project_id = p.id AND d.deleted_at IS NULL AND d.status = 'READY' AND d.target = 'production'
+			ORDER BY COALESCE(d.promoted_at, d.created_at) DESC, d.id DESC LIMIT 1), 0),
+		pa.access_mode, pa.username, pa.password_hash, pa.allowed_cidrs
+	FROM projects p LEFT JOIN project_access pa ON pa.project_id = p.id
+	WHERE p.deleted_at IS NULL`
+
+// newRoutingTable connects to the project database and loads every route
+func newRoutingTable(databaseURL string) *routingTable {
+	if databaseURL == "" {
+		log.Fatal("DB_URL is required to load the routing table")
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		log.Fatalf("Unable to open database: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Unable to reach database: %v", err)
+	}
+
+	table := &routingTable{
+		db:          db,
+		databaseURL: databaseURL,
+		projects:    make(map[int]*projectConfig),
+		hosts:       make(map[string]*projectConfig),
+	}
+	if err := table.reloadAll(); err != nil {
+		log.Fatalf("Unable to load routing table: %v", err)
+	}
+	return table
+}
+
+// lookup returns the project served on host, nil when the host is unknown
+func (t *routingTable) lookup(host string) *projectConfig {
+	host = normalizeHost(host)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if config, ok := t.hosts[host]; ok {
+		return config
+	}
+	if subdomain, _, found := strings.Cut(host, "."); found {
+		return t.hosts[subdomain]
+	}
+	return nil
+}
+
+// reloadAll replaces the whole table, used at startup and whenever notifications may have been missed
+func (t *routingTable) reloadAll() error {
+	ctx, cancel := context.WithTimeout(context.Background(), routesQueryTimeout)
+	defer cancel()
+
+	configs, err := t.query(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	projects := make(map[int]*projectConfig, len(configs))
+	hosts := make(map[string]*projectConfig, len(configs))
+	for _, config := range configs {
+		projects[config.ProjectId] = config
+		addHosts(hosts, config)
+	}
+
+	t.mu.Lock()
+	t.projects = projects
+	t.hosts = hosts
+	t.mu.Unlock()
+
+	log.Printf("Routing table loaded with %d projects", len(projects))
+	return nil
+}
+
+// reloadProject refreshes the routes of a single project, removing them when the project no longer exists
+func (t *routingTable) reloadProject(projectId int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), routesQueryTimeout)
+	defer cancel()
+
+	configs, err := t.query(ctx, "AND p.id = $1", projectId)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if previous, ok := t.projects[projectId]; ok {
+		for _, host := range hostsOf(previous) {
+			if t.hosts[host] == previous {
+				delete(t.hosts, host)
+			}
+		}
+		delete(t.projects, projectId)
+	}
+	for _, config := range configs {
+		t.projects[config.ProjectId] = config
+		addHosts(t.hosts, config)
+	}
+	return nil
+}
+
+// listen applies change notifications until ctx is cancelled, the table is fully reloaded after
+// every reconnect and periodically in case a notification was lost
+func (t *routingTable) listen(ctx context.Context) {
+	listener := pq.NewListener(t.databaseURL, listenerMinReconnect, listenerMaxReconnect,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Printf("Routing table listener event %d: %v", event, err)
+			}
+		})
+	defer listener.Close()
+
+	if err := listener.Listen(routesChannel); err != nil {
+		log.Printf("Unable to listen for route changes: %v", err)
+		return
+	}
+
+	resync := time.NewTicker(routesResyncInterval)
+	defer resync.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-listener.Notify:
+			// a nil notification means the connection was re-established and changes may have been missed
+			if notification == nil {
+				t.logReload(t.reloadAll())
+				continue
+			}
+			projectId, err := strconv.Atoi(notification.Extra)
+			if err != nil {
+				log.Printf("Ignoring route change with invalid payload %q", notification.Extra)
+				continue
+			}
+			t.logReload(t.reloadProject(projectId))
+		case <-resync.C:
+			t.logReload(t.reloadAll())
+		case <-time.After(listenerPingInterval):
+			if err := listener.Ping(); err != nil {
+				log.Printf("Routing table listener ping failed: %v", err)
+			}
+		}
+	}
+}
+
+func (t *routingTable) logReload(err error) {
+	if err != nil {
+		log.Printf("Failed to reload routing table: %v", err)
+	}
+}
+
+func (t *routingTable) query(ctx context.Context, condition string, args ...interface{}) ([]*projectConfig, error) {
+	rows, err := t.db.QueryContext(ctx, routesQuery+" "+condition, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query routes: %w", err)
+	}
+	defer rows.Close()
+
+	var configs []*projectConfig
+	for rows.Next() {
+		var config projectConfig
+		var mode, username, passwordHash sql.NullString
+		var cidrs []string
+		err := rows.Scan(
+			&config.ProjectId, &config.Subdomain, &config.CustomDomain, &config.MaintenanceMode, &config.MaintenancePage,
+			&config.LatestStatus, &config.DeploymentId,
+			&mode, &username, &passwordHash, pq.Array(&cidrs),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan route: %w", err)
+		}
+
+		if mode.Valid {
+			config.Access = &accessConfig{
+				ProjectId:    config.ProjectId,
+				Mode:         mode.String,
+				Username:     username.String,
+				PasswordHash: passwordHash.String,
+				AllowedNets:  parseCIDRs(config.ProjectId, cidrs),
+			}
+		}
+		configs = append(configs, &config)
+	}
+	return configs, rows.Err()
+}
+
+func addHosts(hosts map[string]*projectConfig, config *projectConfig) {
+	for _, host := range hostsOf(config) {
+		hosts[host] = config
+	}
+}
+
+// hostsOf returns the subdomain and custom domain the project is served on
+func hostsOf(config *projectConfig) []string {
+	var hosts []string
+	if config.Subdomain != "" {
+		hosts = append(hosts, strings.ToLower(config.Subdomain))
+	}
+	if config.CustomDomain != "" {
+		hosts = append(hosts, normalizeHost(config.CustomDomain))
+	}
+	return hosts
+}
+
+// normalizeHost lowercases the host and strips the port and trailing dot
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+func parseCIDRs(projectId int, cidrs []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Printf("Skipping invalid cidr %q for project %d", cidr, projectId)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
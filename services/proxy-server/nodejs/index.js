@@ -259,7 +259,7 @@ const getProjectBySubdomain = async (pool, subdomain) => {
     const query = `
       SELECT id, subdomain, created_at
       FROM projects 
-      WHERE subdomain = $1 
+      WHERE subdomain = $1 AND deleted_at IS NULL
       LIMIT 1;
     `;
     const result = await pool.query(query, [subdomain]);
@@ -286,8 +286,8 @@ const getLatestDeployment = async (pool, projectId) => {
     const query = `
       SELECT id, project_id, status, created_at
       FROM deployments 
-      WHERE project_id = $1 AND status = 'READY' 
-      ORDER BY created_at DESC 
+      WHERE project_id = $1 AND status = 'READY' AND target = 'production' AND deleted_at IS NULL
+      ORDER BY COALESCE(promoted_at, created_at) DESC, id DESC
       LIMIT 1;
     `;
     const result = await pool.query(query, [projectId]);
@@ -2,11 +2,19 @@ package conf
 
 import "os"
 
+var DB_URL = os.Getenv("DB_URL")
 var AWS_TOKEN = os.Getenv("AWS_TOKEN")
 var AWS_SQS_URL = os.Getenv("SQS_URL")
+var AWS_DLQ_URL = os.Getenv("DLQ_URL")
 var AWS_REGION = os.Getenv("AWS_REGION")
 var AWS_ACCESS_KEY = os.Getenv("AWS_ACCESS_KEY")
 var AWS_SECRET_ACCESS_KEY = os.Getenv("AWS_SECRET_ACCESS_KEY")
 
-const VisibilityTimeout = 10
+// AWS_SQS_ENDPOINT points the consumer at a local emulator such as ElasticMQ or LocalStack when set
+var AWS_SQS_ENDPOINT = os.Getenv("AWS_SQS_ENDPOINT")
+
+const Workers = 10
+const WaitTimeSeconds = 20
+const VisibilityTimeout = 30
+const MaxReceives = 5
 const MaxNumberOfMessages = 10
@@ -0,0 +1,8 @@
+package db
+
+const (
+	ENV_PROD  = "prod"
+	ENV_UAT   = "uat"
+	ENV_DEV   = "dev"
+	ENV_LOCAL = "local"
+)
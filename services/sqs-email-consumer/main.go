@@ -1,74 +1,208 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/joho/godotenv"
 	"github.com/swarajkumarsingh/go-email-consumer-aws-sqs/conf"
+	"github.com/swarajkumarsingh/go-email-consumer-aws-sqs/db"
 	"github.com/swarajkumarsingh/go-email-consumer-aws-sqs/ses"
+	"github.com/swarajkumarsingh/go-email-consumer-aws-sqs/templates"
 	"github.com/swarajkumarsingh/go-email-consumer-aws-sqs/utils"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs/consumer"
 )
 
 var sesDefaultSender string = os.Getenv("SES_DEFAULT_SENDER")
 
+var database = db.Mgr.DBConn
+
+const charSet = "UTF-8"
+
 type Queue struct {
-	AppName        string `json:"appName"`
-	ProjectId      string `json:"projectId"`
-	DeploymentId   string `json:"deploymentId"`
-	RecipientEmail string `json:"recipient_email"`
-	Subject        string `json:"subject"`
-	Body           string `json:"body"`
-	Timestamp      string `json:"timestamp"`
+	AppName      string `json:"appName"`
+	ProjectId    string `json:"projectId"`
+	DeploymentId string `json:"deploymentId"`
+	ChannelId    string `json:"channelId"`
+	Code         string `json:"code"`
+	Event        string `json:"event"`
+	URL          string `json:"url"`
+	Error        string `json:"error"`
+	Resource     string `json:"resource"`
+	Used         string `json:"used"`
+	Limit        string `json:"limit"`
+	Timestamp    string `json:"timestamp"`
 }
 
-func processMessage(sqsSvc *sqs.SQS, msg *sqs.Message) error {
+// handleMessage renders a notification email and sends it to the project owner and the verified email channels
+// of the project, owners who opted out of the event and undeliverable messages are skipped
+func handleMessage(ctx context.Context, msg *sqs.Message) error {
 	body := aws.StringValue(msg.Body)
-	receiptHandle := msg.ReceiptHandle
 
 	var queue Queue
 	if err := json.Unmarshal([]byte(body), &queue); err != nil {
 		log.Printf("Error un-marshalling message body: %v", err)
-		return err
+		return consumer.Permanent(err)
 	}
 
-	log.Printf("AppName: %s, Recipient Email: %s, ProjectId: %s, DeploymentId: %s, Timestamp: %s",
-		queue.AppName, queue.RecipientEmail, queue.ProjectId, queue.DeploymentId, queue.Timestamp)
+	log.Printf("AppName: %s, Event: %s, ProjectId: %s, DeploymentId: %s, Timestamp: %s",
+		queue.AppName, queue.Event, queue.ProjectId, queue.DeploymentId, queue.Timestamp)
 
-	valid := utils.ValidEmail(queue.RecipientEmail)
-	if !valid {
-		log.Println("invalid email address: ", queue.RecipientEmail)
-		if err := deleteMessage(sqsSvc, receiptHandle); err != nil {
-			return err
-		}
+	if !templates.Supported(queue.Event) {
+		return consumer.Permanent(fmt.Errorf("unsupported event %q", queue.Event))
+	}
+	if _, err := strconv.Atoi(queue.ProjectId); err != nil {
+		return consumer.Permanent(fmt.Errorf("invalid project id %q", queue.ProjectId))
+	}
+
+	if queue.Event == templates.ChannelVerification {
+		return sendChannelVerification(ctx, queue)
 	}
 
-	_, err := ses.SendEmail(sesDefaultSender, queue.RecipientEmail, queue.Subject, queue.Body, "test", "UTF-8")
+	owner, enabled, err := getRecipient(ctx, queue.ProjectId, queue.Event)
 	if err != nil {
-		log.Printf("Error sending email: %v", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return consumer.Permanent(fmt.Errorf("project %s not found", queue.ProjectId))
+		}
+		return err
 	}
 
-	if err := deleteMessage(sqsSvc, receiptHandle); err != nil {
+	var recipients []string
+	switch {
+	case !enabled:
+		log.Printf("Skipping %s email to the owner of project %s, they opted out", queue.Event, queue.ProjectId)
+	case !utils.ValidEmail(owner):
+		log.Println("invalid email address: ", owner)
+	default:
+		recipients = append(recipients, owner)
+	}
+
+	channels, err := getChannelRecipients(ctx, queue.ProjectId, queue.Event)
+	if err != nil {
 		return err
 	}
+	for _, channel := range channels {
+		// the owner gets a single email when they also added their own address as a channel
+		if len(recipients) > 0 && strings.EqualFold(channel, owner) {
+			continue
+		}
+		recipients = append(recipients, channel)
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	email, err := templates.Render(queue.Event, templates.Data{
+		AppName:      queue.AppName,
+		ProjectId:    queue.ProjectId,
+		DeploymentId: queue.DeploymentId,
+		URL:          queue.URL,
+		Error:        queue.Error,
+		Resource:     queue.Resource,
+		Used:         queue.Used,
+		Limit:        queue.Limit,
+	})
+	if err != nil {
+		return consumer.Permanent(err)
+	}
 
+	// the first recipient is retried with the message, later ones are logged so nobody gets the email twice
+	for i, recipient := range recipients {
+		_, err := ses.SendEmail(sesDefaultSender, recipient, email.Subject, email.HTML, email.Text, charSet)
+		if err != nil && i == 0 {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		if err != nil {
+			log.Printf("Error sending %s email for project %s to a notification channel: %v", queue.Event, queue.ProjectId, err)
+		}
+	}
 	return nil
 }
 
-func deleteMessage(sqsSvc *sqs.SQS, receiptHandle *string) error {
-	_, err := sqsSvc.DeleteMessage(&sqs.DeleteMessageInput{
-		QueueUrl:      aws.String(conf.AWS_SQS_URL),
-		ReceiptHandle: receiptHandle,
+// sendChannelVerification emails the code to a new email channel, the address is looked up by channel so a
+// message can only reach addresses the project owner added
+func sendChannelVerification(ctx context.Context, queue Queue) error {
+	if _, err := strconv.Atoi(queue.ChannelId); err != nil || queue.Code == "" {
+		return consumer.Permanent(fmt.Errorf("invalid channel verification for channel %q", queue.ChannelId))
+	}
+
+	var channel struct {
+		Target      string `db:"target"`
+		ProjectName string `db:"name"`
+	}
+	query := `SELECT c.target, p.name FROM notification_channels c JOIN projects p ON p.id = c.project_id
+		WHERE c.id = $1 AND c.project_id = $2 AND c.type = 'email' AND c.verified_at IS NULL`
+	err := database.GetContext(ctx, &channel, query, queue.ChannelId, queue.ProjectId)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Skipping verification of channel %s, it was verified or deleted", queue.ChannelId)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !utils.ValidEmail(channel.Target) {
+		log.Println("invalid email address: ", channel.Target)
+		return nil
+	}
+
+	email, err := templates.Render(queue.Event, templates.Data{
+		AppName:   channel.ProjectName,
+		ProjectId: queue.ProjectId,
+		Code:      queue.Code,
 	})
 	if err != nil {
-		log.Printf("Error deleting message: %v", err)
+		return consumer.Permanent(err)
+	}
+
+	if _, err := ses.SendEmail(sesDefaultSender, channel.Target, email.Subject, email.HTML, email.Text, charSet); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
 	}
-	return err
+	return nil
+}
+
+// channelEvents are the events also sent to the verified email channels of a project, with whether they are
+// a success
+var channelEvents = map[string]bool{templates.DeploymentSuccess: true, templates.DeploymentFailure: false}
+
+// getChannelRecipients returns the addresses of the active email channels of the project that want event
+func getChannelRecipients(ctx context.Context, projectId, event string) ([]string, error) {
+	success, ok := channelEvents[event]
+	if !ok {
+		return nil, nil
+	}
+
+	var recipients []string
+	query := `SELECT DISTINCT target FROM notification_channels
+		WHERE project_id = $1 AND type = 'email' AND active AND verified_at IS NOT NULL
+			AND (CASE WHEN $2 THEN on_success ELSE on_failure END)`
+	err := database.SelectContext(ctx, &recipients, query, projectId, success)
+	return recipients, err
+}
+
+// getRecipient returns the email of the project owner and whether they want to be notified of event,
+// notifications are enabled until the owner opts out
+func getRecipient(ctx context.Context, projectId, event string) (string, bool, error) {
+	// event is one of the supported templates, which are also the preference column names
+	query := fmt.Sprintf(`SELECT u.email, COALESCE(np.%s, TRUE)
+		FROM projects p
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN notification_preferences np ON np.user_id = u.id
+		WHERE p.id = $1`, event)
+
+	var email string
+	var enabled bool
+	err := database.QueryRowContext(ctx, query, projectId).Scan(&email, &enabled)
+	return email, enabled, err
 }
 
 func main() {
@@ -82,36 +216,33 @@ func main() {
 		log.Panicln("Invalid sender email", sesDefaultSender)
 	}
 
-	sess, err := session.NewSession(&aws.Config{
+	awsConfig := &aws.Config{
 		Region: aws.String(conf.AWS_REGION),
-	})
+	}
+	if conf.AWS_SQS_ENDPOINT != "" {
+		awsConfig.Endpoint = aws.String(conf.AWS_SQS_ENDPOINT)
+	}
+
+	sess, err := session.NewSession(awsConfig)
 	if err != nil {
 		log.Fatalf("Error creating session: %v", err)
 	}
 
-	sqsSvc := sqs.New(sess)
-
-	log.Println("Listening to messages")
-	for {
-		// Receive messages from SQS queue
-		msgResult, err := sqsSvc.ReceiveMessage(&sqs.ReceiveMessageInput{
-			QueueUrl:            aws.String(conf.AWS_SQS_URL),
-			MaxNumberOfMessages: aws.Int64(conf.MaxNumberOfMessages),
-			VisibilityTimeout:   aws.Int64(conf.VisibilityTimeout),
-		})
-		if err != nil {
-			log.Printf("Error receiving message: %v", err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-
-		// Process received messages
-		for _, msg := range msgResult.Messages {
-			if err := processMessage(sqsSvc, msg); err != nil {
-				log.Printf("Error processing message: %v", err)
-			}
-		}
-
-		time.Sleep(500 * time.Millisecond)
+	ctx, stop := consumer.WithShutdownSignals(context.Background())
+	defer stop()
+
+	worker := consumer.New(sqs.New(sess), consumer.Config{
+		QueueURL:           conf.AWS_SQS_URL,
+		Workers:            conf.Workers,
+		MaxMessages:        conf.MaxNumberOfMessages,
+		WaitTimeSeconds:    conf.WaitTimeSeconds,
+		VisibilityTimeout:  conf.VisibilityTimeout,
+		MaxReceives:        conf.MaxReceives,
+		DeadLetterQueueURL: conf.AWS_DLQ_URL,
+		Quarantine:         consumer.NewPostgresQuarantine(database),
+	}, consumer.HandlerFunc(handleMessage))
+
+	if err := worker.Run(ctx); err != nil {
+		log.Fatalf("Error running consumer: %v", err)
 	}
 }
@@ -1 +1 @@
-make run STAGE= SQS_URL= AWS_REGION= AWS_TOKEN= SES_DEFAULT_SENDER= AWS_ACCESS_KEY= AWS_SECRET_ACCESS_KEY=
\ No newline at end of file
+make run STAGE= DB_URL= SQS_URL= AWS_REGION= AWS_TOKEN= SES_DEFAULT_SENDER= AWS_ACCESS_KEY= AWS_SECRET_ACCESS_KEY=
\ No newline at end of file
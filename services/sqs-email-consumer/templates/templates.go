@@ -0,0 +1,88 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+const (
+	DeploymentSuccess   = "deployment_success"
+	DeploymentFailure   = "deployment_failure"
+	DeploymentCancelled = "deployment_cancelled"
+	QuotaWarning        = "quota_warning"
+	ChannelVerification = "channel_verification"
+)
+
+var subjects = map[string]string{
+	DeploymentSuccess:   "%s deployed successfully | Turbo-Deploy",
+	DeploymentFailure:   "%s deployment failed | Turbo-Deploy",
+	DeploymentCancelled: "%s deployment cancelled | Turbo-Deploy",
+	QuotaWarning:        "%s is close to its quota | Turbo-Deploy",
+	ChannelVerification: "Verify your email for %s notifications | Turbo-Deploy",
+}
+
+//go:embed html/*.html text/*.txt
+var files embed.FS
+
+var (
+	htmlTemplates = make(map[string]*htmltemplate.Template, len(subjects))
+	textTemplates = make(map[string]*texttemplate.Template, len(subjects))
+)
+
+// Data is what the templates render, fields that do not apply to an event are left empty
+type Data struct {
+	AppName      string
+	ProjectId    string
+	DeploymentId string
+	URL          string
+	Error        string
+	Resource     string
+	Used         string
+	Limit        string
+	Code         string
+}
+
+// Email is a rendered message with both parts, mail clients without html support show Text
+type Email struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// every event is parsed with its own copy of the layout since they all define the same blocks
+func init() {
+	for event := range subjects {
+		htmlTemplates[event] = htmltemplate.Must(htmltemplate.ParseFS(files, "html/layout.html", "html/"+event+".html"))
+		textTemplates[event] = texttemplate.Must(texttemplate.ParseFS(files, "text/layout.txt", "text/"+event+".txt"))
+	}
+}
+
+// Supported reports whether there is a template for event
+func Supported(event string) bool {
+	_, ok := subjects[event]
+	return ok
+}
+
+// Render builds the email sent for event
+func Render(event string, data Data) (Email, error) {
+	if !Supported(event) {
+		return Email{}, fmt.Errorf("no template for event %q", event)
+	}
+
+	var html, text bytes.Buffer
+	if err := htmlTemplates[event].ExecuteTemplate(&html, "layout", data); err != nil {
+		return Email{}, fmt.Errorf("failed to render html for %s: %w", event, err)
+	}
+	if err := textTemplates[event].ExecuteTemplate(&text, "layout", data); err != nil {
+		return Email{}, fmt.Errorf("failed to render text for %s: %w", event, err)
+	}
+
+	return Email{
+		Subject: fmt.Sprintf(subjects[event], data.AppName),
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
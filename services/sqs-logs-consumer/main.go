@@ -4,14 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"log"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/joho/godotenv"
+	"github.com/lib/pq"
 	"github.com/swarajkumarsingh/go-email-consumer-aws-sqs/conf"
 	"github.com/swarajkumarsingh/go-email-consumer-aws-sqs/db"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs/consumer"
 )
 
 var database = db.Mgr.DBConn
@@ -30,37 +31,84 @@ type Queue struct {
 	Timestamp    string `json:"timestamp"`
 }
 
-func processMessage(sqsSvc *sqs.SQS, msg *sqs.Message) error {
-	body := aws.StringValue(msg.Body)
-	receiptHandle := msg.ReceiptHandle
+var deploymentLogColumns = []string{"deployment_id", "project_id", "environment", "message", "cause", "stack", "name", "host", "log_type", "timestamp"}
 
-	var queue Queue
-	if err := json.Unmarshal([]byte(body), &queue); err != nil {
-		log.Printf("Error un-marshalling message body: %v", err)
+// handleBatch stores the log lines of a batch in one transaction, messages without a log line are acknowledged
+// and dropped, malformed messages are rejected individually
+func handleBatch(ctx context.Context, msgs []*sqs.Message) []error {
+	errs := make([]error, len(msgs))
+	entries := make([]Queue, 0, len(msgs))
+	indexes := make([]int, 0, len(msgs))
+
+	for i, msg := range msgs {
+		var queue Queue
+		if err := json.Unmarshal([]byte(aws.StringValue(msg.Body)), &queue); err != nil {
+			log.Printf("Error un-marshalling message body: %v", err)
+			errs[i] = consumer.Permanent(err)
+			continue
+		}
+		if queue.Message == "" {
+			log.Println("Deleting message with empty 'Message' field")
+			continue
+		}
+		entries = append(entries, queue)
+		indexes = append(indexes, i)
+	}
+
+	if len(entries) == 0 {
+		return errs
+	}
+
+	err := CreateDeploymentLogs(ctx, entries)
+	if err == nil {
+		log.Printf("Stored %d deployment logs", len(entries))
+		return errs
+	}
+	log.Printf("Error while copying %d logs to DB, retrying individually: %v", len(entries), err)
+
+	// the batch failed as a whole, insert one by one so a single bad row does not hold back the others
+	for i, entry := range entries {
+		if err := CreateDeploymentLog(ctx, entry); err != nil {
+			log.Println("Error while pushing to DB:", err.Error())
+			errs[indexes[i]] = err
+		}
+	}
+	return errs
+}
+
+// CreateDeploymentLogs writes all entries with COPY in a single transaction
+func CreateDeploymentLogs(ctx context.Context, entries []Queue) error {
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	log.Printf("AppName: %s, Message: %s, LogType: %s, ProjectId: %s, DeploymentId: %s, Host: %s, Environment: %s, Cause: %s, Stack: %s, Timestamp: %s",
-		queue.AppName, queue.Message, queue.LogType, queue.ProjectId, queue.DeploymentId, queue.Host, queue.Environment, queue.Cause, queue.Stack, queue.Timestamp)
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("deployment_logs", deploymentLogColumns...))
+	if err != nil {
+		return err
+	}
 
-	if queue.Message == "" {
-		log.Println("Deleting message with empty 'Message' field")
-		if err := deleteMessage(sqsSvc, receiptHandle); err != nil {
+	for _, body := range entries {
+		if body.LogType == "" {
+			body.LogType = "INFO"
+		}
+		_, err := stmt.ExecContext(ctx, body.DeploymentId, body.ProjectId, body.Environment, body.Message, body.Cause, body.Stack, body.Name, body.Host, body.LogType, body.Timestamp)
+		if err != nil {
+			stmt.Close()
 			return err
 		}
-		return nil
 	}
 
-	if err := CreateDeploymentLog(context.Background(), queue); err != nil {
-		log.Println("Error while pushing to DB:", err.Error())
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
 		return err
 	}
-
-	if err := deleteMessage(sqsSvc, receiptHandle); err != nil {
+	if err := stmt.Close(); err != nil {
 		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 func CreateDeploymentLog(context context.Context, body Queue) error {
@@ -77,52 +125,41 @@ func CreateDeploymentLog(context context.Context, body Queue) error {
 	return nil
 }
 
-func deleteMessage(sqsSvc *sqs.SQS, receiptHandle *string) error {
-	_, err := sqsSvc.DeleteMessage(&sqs.DeleteMessageInput{
-		QueueUrl:      aws.String(conf.AWS_SQS_URL),
-		ReceiptHandle: receiptHandle,
-	})
-	if err != nil {
-		log.Printf("Error deleting message: %v", err)
-	}
-	return err
-}
-
 func main() {
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatalf("Error loading .env file")
 	}
 
-	sess, err := session.NewSession(&aws.Config{
+	awsConfig := &aws.Config{
 		Region: aws.String(conf.AWS_REGION),
-	})
+	}
+	if conf.AWS_SQS_ENDPOINT != "" {
+		awsConfig.Endpoint = aws.String(conf.AWS_SQS_ENDPOINT)
+	}
+
+	sess, err := session.NewSession(awsConfig)
 	if err != nil {
 		log.Fatalf("Error creating session: %v", err)
 	}
 
-	sqsSvc := sqs.New(sess)
-
-	log.Println("Listening to messages")
-
-	for {
-		msgResult, err := sqsSvc.ReceiveMessage(&sqs.ReceiveMessageInput{
-			QueueUrl:            aws.String(conf.AWS_SQS_URL),
-			MaxNumberOfMessages: aws.Int64(conf.MaxNumberOfMessages),
-			VisibilityTimeout:   aws.Int64(conf.VisibilityTimeout),
-		})
-		if err != nil {
-			log.Printf("Error receiving message: %v", err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-
-		for _, msg := range msgResult.Messages {
-			if err := processMessage(sqsSvc, msg); err != nil {
-				log.Printf("Error processing message: %v", err)
-			}
-		}
-
-		time.Sleep(500 * time.Millisecond)
+	ctx, stop := consumer.WithShutdownSignals(context.Background())
+	defer stop()
+
+	worker := consumer.NewBatch(sqs.New(sess), consumer.Config{
+		QueueURL:           conf.AWS_SQS_URL,
+		Workers:            conf.Workers,
+		MaxMessages:        conf.MaxNumberOfMessages,
+		WaitTimeSeconds:    conf.WaitTimeSeconds,
+		VisibilityTimeout:  conf.VisibilityTimeout,
+		MaxReceives:        conf.MaxReceives,
+		DeadLetterQueueURL: conf.AWS_DLQ_URL,
+		Quarantine:         consumer.NewPostgresQuarantine(database),
+		BatchSize:          conf.BatchSize,
+		BatchWindow:        conf.BatchWindow,
+	}, consumer.BatchHandlerFunc(handleBatch))
+
+	if err := worker.Run(ctx); err != nil {
+		log.Fatalf("Error running consumer: %v", err)
 	}
 }
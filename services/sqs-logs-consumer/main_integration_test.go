@@ -0,0 +1,132 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs/consumer"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs/memory"
+)
+
+const testQueueURL = "memory://logs"
+
+// TestLogsPipeline publishes build server log lines to the in-memory queue and consumes them with handleBatch.
+// It needs DB_URL to point at a migrated database: DB_URL=... go test -tags integration .
+func TestLogsPipeline(t *testing.T) {
+	ctx := context.Background()
+	queue := memory.New()
+	sqs.SetClient(queue)
+
+	projectId, deploymentId := seedDeployment(t, ctx)
+	lines := []struct{ message, logType string }{
+		{"Build Started...", "INFO"},
+		{"npm warn deprecated", "WARN"},
+		{"Build failed", "ERROR"},
+		// lines without a message are acknowledged and dropped
+		{"", "INFO"},
+	}
+	for _, line := range lines {
+		publishLog(t, projectId, deploymentId, line.message, line.logType)
+	}
+
+	consume(t, queue, consumer.NewBatch(queue, consumer.Config{
+		QueueURL:        testQueueURL,
+		Workers:         1,
+		WaitTimeSeconds: 1,
+		BatchSize:       10,
+		BatchWindow:     100 * time.Millisecond,
+	}, consumer.BatchHandlerFunc(handleBatch)))
+
+	var stored []struct {
+		Message string `db:"message"`
+		LogType string `db:"log_type"`
+	}
+	err := database.SelectContext(ctx, &stored, `SELECT message, log_type FROM deployment_logs WHERE deployment_id = $1 ORDER BY id`,
+		deploymentId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 3 {
+		t.Fatalf("stored %d logs, want 3: %+v", len(stored), stored)
+	}
+	for i, entry := range stored {
+		if entry.Message != lines[i].message || entry.LogType != lines[i].logType {
+			t.Errorf("log %d = %+v, want %+v", i, entry, lines[i])
+		}
+	}
+}
+
+func publishLog(t *testing.T, projectId, deploymentId int, message, logType string) {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{
+		"appName":      "integration-test",
+		"message":      message,
+		"logType":      logType,
+		"projectId":    strconv.Itoa(projectId),
+		"deploymentId": strconv.Itoa(deploymentId),
+		"environment":  "dev",
+		"host":         "localhost",
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sqs.SendMessage(string(body), testQueueURL); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// consume runs the consumer until every message was handled and deleted
+func consume(t *testing.T, queue *memory.Queue, worker *consumer.Consumer) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- worker.Run(ctx) }()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for queue.Len(testQueueURL) > 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if remaining := queue.Len(testQueueURL); remaining > 0 {
+		t.Fatalf("%d messages were not consumed", remaining)
+	}
+}
+
+// seedDeployment creates a user with a project and a queued deployment, they are removed when the test ends
+func seedDeployment(t *testing.T, ctx context.Context) (projectId, deploymentId int) {
+	t.Helper()
+	suffix := time.Now().UnixNano()
+
+	var userId int
+	err := database.GetContext(ctx, &userId, `INSERT INTO users(username, firstname, lastname, email, password, phone)
+		VALUES($1, 'integration', 'test', $2, '', '0000000000') RETURNING id`,
+		fmt.Sprintf("integration-%d", suffix), fmt.Sprintf("integration-%d@example.com", suffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// projects, deployments and their logs cascade
+	t.Cleanup(func() { database.Exec(`DELETE FROM users WHERE id = $1`, userId) })
+
+	err = database.GetContext(ctx, &projectId, `INSERT INTO projects(user_id, name, source_code_url, subdomain)
+		VALUES($1, 'integration', 'https://github.com/example/site', $2) RETURNING id`, userId, fmt.Sprintf("integration%d", suffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = database.GetContext(ctx, &deploymentId, `INSERT INTO deployments(user_id, project_id) VALUES($1, $2) RETURNING id`,
+		userId, projectId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return projectId, deploymentId
+}
@@ -5,8 +5,18 @@ import "os"
 var DB_URL = os.Getenv("DB_URL")
 var AWS_REGION = os.Getenv("AWS_REGION")
 var AWS_SQS_URL = os.Getenv("AWS_SQS_URL")
+var AWS_DLQ_URL = os.Getenv("AWS_DLQ_URL")
 var AWS_ACCESS_KEY = os.Getenv("AWS_ACCESS_KEY")
 var AWS_SECRET_ACCESS_KEY = os.Getenv("AWS_SECRET_ACCESS_KEY")
 
-const VisibilityTimeout = 10
+// AWS_SQS_ENDPOINT points the consumer at a local emulator such as ElasticMQ or LocalStack when set
+var AWS_SQS_ENDPOINT = os.Getenv("AWS_SQS_ENDPOINT")
+
+// PROXY_DOMAIN is the domain the proxy serves project subdomains on, used to build the ready url
+var PROXY_DOMAIN = os.Getenv("PROXY_DOMAIN")
+
+const Workers = 10
+const WaitTimeSeconds = 20
+const VisibilityTimeout = 30
+const MaxReceives = 5
 const MaxNumberOfMessages = 10
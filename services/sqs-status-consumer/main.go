@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -12,10 +16,16 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/swarajkumarsingh/status-sqs-consumer/conf"
 	"github.com/swarajkumarsingh/status-sqs-consumer/db"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/notifier"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/outbox"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs/consumer"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/webhook"
 )
 
 var database = db.Mgr.DBConn
 
+var router = notifier.NewRouter(database)
+
 type Queue struct {
 	AppName      string `json:"appName"`
 	ProjectId    string `json:"projectId"`
@@ -23,63 +33,195 @@ type Queue struct {
 	Host         string `json:"host"`
 	Status       string `json:"Status"`
 	Timestamp    string `json:"timestamp"`
+	// Commit details are what the build server checked out, empty for uploads
+	CommitSha     string `json:"commitSha"`
+	Branch        string `json:"branch"`
+	CommitMessage string `json:"commitMessage"`
+	CommitAuthor  string `json:"commitAuthor"`
 }
 
-func processMessage(sqsSvc *sqs.SQS, msg *sqs.Message) error {
+// handleMessage applies a single status update, messages without a status are acknowledged and dropped
+func handleMessage(ctx context.Context, msg *sqs.Message) error {
 	body := aws.StringValue(msg.Body)
-	receiptHandle := msg.ReceiptHandle
 
 	var queue Queue
 	if err := json.Unmarshal([]byte(body), &queue); err != nil {
 		log.Printf("Error un-marshalling message body: %v", err)
-		return err
+		return consumer.Permanent(err)
 	}
 
 	log.Printf("AppName: %s, ProjectId: %s, DeploymentId: %s, Host: %s, Status: %s, Timestamp: %s",
 		queue.AppName, queue.ProjectId, queue.DeploymentId, queue.Host, queue.Status, queue.Timestamp)
 
 	if queue.Status == "" {
-		log.Println("Deleting message with empty 'Message' field")
-		if err := deleteMessage(sqsSvc, receiptHandle); err != nil {
-			return err
-		}
+		log.Println("Deleting message with empty 'Status' field")
 		return nil
 	}
+	if !validStatuses[queue.Status] {
+		return consumer.Permanent(fmt.Errorf("unknown status %q", queue.Status))
+	}
+	if _, err := strconv.Atoi(queue.DeploymentId); err != nil {
+		return consumer.Permanent(fmt.Errorf("invalid deployment id %q", queue.DeploymentId))
+	}
 
-	if err := CreateDeploymentLog(context.Background(), queue); err != nil {
+	transition, err := UpdateDeploymentStatus(ctx, queue, aws.StringValue(msg.MessageId))
+	if err != nil {
 		log.Println("Error while pushing to DB:", err.Error())
 		return err
 	}
-
-	if err := deleteMessage(sqsSvc, receiptHandle); err != nil {
-		return err
+	if transition == nil {
+		log.Printf("Ignoring transition of deployment %s to %s, it is stale or already applied", queue.DeploymentId, queue.Status)
+		return nil
 	}
 
+	if queue.Status == notifier.StatusReady || queue.Status == notifier.StatusFail {
+		notifyChannels(ctx, queue, transition)
+	}
 	return nil
 }
 
-func CreateDeploymentLog(context context.Context, body Queue) error {
-	if body.Status == "" {
-		body.Status = "PROG"
+// validStatuses are the statuses the build server reports, QUEUE is only ever set by the api
+var validStatuses = map[string]bool{"PROG": true, "READY": true, "FAIL": true}
+
+// updateStatusQuery moves a deployment along QUEUE -> PROG -> READY | FAIL and records the transition.
+// READY and FAIL are terminal, so late or duplicate messages match no row and are skipped. The duration
+// is measured from the start of the build, or from queueing when no PROG update arrived. Only production
+// deployments get the project url, previews are reached through promotion.
+const updateStatusQuery = `WITH previous AS (
+		SELECT d.id, d.status, d.created_at, d.target, p.subdomain FROM deployments d
+		JOIN projects p ON p.id = d.project_id
+		WHERE d.id = $1 FOR UPDATE OF d
+	), updated AS (
+		UPDATE deployments d SET status = $2::status_enum, updated_at = NOW(),
+			duration = CASE WHEN $2::status_enum IN ('READY', 'FAIL') THEN EXTRACT(EPOCH FROM NOW() - COALESCE(
+				(SELECT e.created_at FROM deployment_status_events e WHERE e.deployment_id = d.id AND e.to_status = 'PROG'),
+				previous.created_at))::INT ELSE d.duration END,
+			ready_url = CASE WHEN $2::status_enum = 'READY' AND $3::text <> '' AND previous.subdomain IS NOT NULL
+				AND previous.target = 'production' THEN 'https://' || previous.subdomain || '.' || $3::text ELSE d.ready_url END,
+			commit_sha = COALESCE(NULLIF($6::text, ''), d.commit_sha),
+			branch = COALESCE(NULLIF($7::text, ''), d.branch),
+			commit_message = COALESCE(NULLIF(LEFT($8::text, 4096), ''), d.commit_message),
+			commit_author = COALESCE(NULLIF(LEFT($9::text, 255), ''), d.commit_author)
+		FROM previous
+		WHERE d.id = previous.id AND (
+			(previous.status = 'QUEUE' AND $2::status_enum IN ('PROG', 'READY', 'FAIL')) OR
+			(previous.status = 'PROG' AND $2::status_enum IN ('READY', 'FAIL')))
+		RETURNING previous.status AS from_status, d.project_id, COALESCE(d.ready_url, '') AS ready_url
+	), events AS (
+		INSERT INTO deployment_status_events(deployment_id, from_status, to_status, message_id, reported_at)
+		SELECT $1, from_status, $2::status_enum, $4, $5 FROM updated
+	)
+	SELECT from_status, project_id, ready_url FROM updated`
+
+// Transition is an applied status change
+type Transition struct {
+	FromStatus string `db:"from_status"`
+	ProjectId  int    `db:"project_id"`
+	ReadyUrl   string `db:"ready_url"`
+}
+
+// UpdateDeploymentStatus applies the transition if it is allowed, it returns nil when it was not. The transition
+// is recorded in the outbox in the same transaction for the relay to publish and deliver to webhooks.
+func UpdateDeploymentStatus(ctx context.Context, body Queue, messageId string) (*Transition, error) {
+	var reportedAt *time.Time
+	if parsed, err := time.Parse(time.RFC3339, body.Timestamp); err == nil {
+		reportedAt = &parsed
 	}
-	query := `UPDATE deployments SET status = $1, updated_at = NOW() WHERE id = $2`
 
-	_, err := database.ExecContext(context, query, body.Status, body.DeploymentId)
+	tx, err := database.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
-}
+	defer tx.Rollback()
 
-func deleteMessage(sqsSvc *sqs.SQS, receiptHandle *string) error {
-	_, err := sqsSvc.DeleteMessage(&sqs.DeleteMessageInput{
-		QueueUrl:      aws.String(conf.AWS_SQS_URL),
-		ReceiptHandle: receiptHandle,
+	var transition Transition
+	err = tx.GetContext(ctx, &transition, updateStatusQuery, body.DeploymentId, body.Status, conf.PROXY_DOMAIN, messageId, reportedAt,
+		body.CommitSha, body.Branch, body.CommitMessage, body.CommitAuthor)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentId, _ := strconv.Atoi(body.DeploymentId)
+	err = outbox.Insert(ctx, tx, outbox.Message{
+		AggregateType: outbox.AggregateDeployment,
+		AggregateId:   body.DeploymentId,
+		EventType:     webhookEvents[body.Status],
+		DedupId:       outbox.DeploymentDedupId(deploymentId, body.Status),
+		Payload: map[string]interface{}{
+			"deployment_id": deploymentId,
+			"project_id":    transition.ProjectId,
+			"from_status":   transition.FromStatus,
+			"status":        body.Status,
+			"ready_url":     transition.ReadyUrl,
+			"timestamp":     body.Timestamp,
+		},
 	})
 	if err != nil {
-		log.Printf("Error deleting message: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &transition, nil
+}
+
+// webhookEvents maps the statuses reported by the build server to outbox events, the outbox relay delivers
+// them to the project webhooks
+var webhookEvents = map[string]string{
+	"PROG":  webhook.EventDeploymentStarted,
+	"READY": webhook.EventDeploymentSucceeded,
+	"FAIL":  webhook.EventDeploymentFailed,
+}
+
+// notificationEventQuery loads what the notifiers show besides the transition itself. Logs arrive on their own
+// queue, so the last error may still be missing when the status is processed first.
+const notificationEventQuery = `SELECT p.name, COALESCE(d.duration, 0) AS duration, d.branch, d.commit_sha, d.commit_message, d.commit_author,
+		COALESCE((SELECT l.message FROM deployment_logs l WHERE l.deployment_id = d.id AND l.log_type = 'ERROR'
+			ORDER BY l.id DESC LIMIT 1), '') AS error_log
+	FROM deployments d JOIN projects p ON p.id = d.project_id WHERE d.id = $1`
+
+// notifyChannels sends a terminal transition to the slack, discord and chat channels of the project,
+// a failing channel is logged and never fails the status update
+func notifyChannels(ctx context.Context, queue Queue, transition *Transition) {
+	deploymentId, _ := strconv.Atoi(queue.DeploymentId)
+
+	var details struct {
+		Name          string `db:"name"`
+		Duration      int    `db:"duration"`
+		Branch        string `db:"branch"`
+		CommitSha     string `db:"commit_sha"`
+		CommitMessage string `db:"commit_message"`
+		CommitAuthor  string `db:"commit_author"`
+		ErrorLog      string `db:"error_log"`
+	}
+	if err := database.GetContext(ctx, &details, notificationEventQuery, deploymentId); err != nil {
+		log.Printf("Error loading notification details for deployment %d: %v", deploymentId, err)
+		return
+	}
+
+	event := notifier.Event{
+		ProjectId:     transition.ProjectId,
+		ProjectName:   details.Name,
+		DeploymentId:  deploymentId,
+		Status:        queue.Status,
+		ReadyUrl:      transition.ReadyUrl,
+		Duration:      time.Duration(details.Duration) * time.Second,
+		Branch:        details.Branch,
+		CommitSha:     details.CommitSha,
+		CommitMessage: details.CommitMessage,
+		CommitAuthor:  details.CommitAuthor,
+	}
+	if queue.Status == notifier.StatusFail {
+		event.ErrorLog = details.ErrorLog
+	}
+
+	if err := router.Route(ctx, event); err != nil {
+		log.Printf("Error notifying channels for deployment %d: %v", deploymentId, err)
 	}
-	return err
 }
 
 func main() {
@@ -88,34 +230,33 @@ func main() {
 		log.Fatalf("Error loading .env file")
 	}
 
-	sess, err := session.NewSession(&aws.Config{
+	awsConfig := &aws.Config{
 		Region: aws.String(conf.AWS_REGION),
-	})
+	}
+	if conf.AWS_SQS_ENDPOINT != "" {
+		awsConfig.Endpoint = aws.String(conf.AWS_SQS_ENDPOINT)
+	}
+
+	sess, err := session.NewSession(awsConfig)
 	if err != nil {
 		log.Fatalf("Error creating session: %v", err)
 	}
 
-	sqsSvc := sqs.New(sess)
-	log.Println("Listening to messages")
-
-	for {
-		msgResult, err := sqsSvc.ReceiveMessage(&sqs.ReceiveMessageInput{
-			QueueUrl:            aws.String(conf.AWS_SQS_URL),
-			MaxNumberOfMessages: aws.Int64(conf.MaxNumberOfMessages),
-			VisibilityTimeout:   aws.Int64(conf.VisibilityTimeout),
-		})
-		if err != nil {
-			log.Printf("Error receiving message: %v", err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-
-		for _, msg := range msgResult.Messages {
-			if err := processMessage(sqsSvc, msg); err != nil {
-				log.Printf("Error processing message: %v", err)
-			}
-		}
-
-		time.Sleep(500 * time.Millisecond)
+	ctx, stop := consumer.WithShutdownSignals(context.Background())
+	defer stop()
+
+	worker := consumer.New(sqs.New(sess), consumer.Config{
+		QueueURL:           conf.AWS_SQS_URL,
+		Workers:            conf.Workers,
+		MaxMessages:        conf.MaxNumberOfMessages,
+		WaitTimeSeconds:    conf.WaitTimeSeconds,
+		VisibilityTimeout:  conf.VisibilityTimeout,
+		MaxReceives:        conf.MaxReceives,
+		DeadLetterQueueURL: conf.AWS_DLQ_URL,
+		Quarantine:         consumer.NewPostgresQuarantine(database),
+	}, consumer.HandlerFunc(handleMessage))
+
+	if err := worker.Run(ctx); err != nil {
+		log.Fatalf("Error running consumer: %v", err)
 	}
 }
@@ -0,0 +1,135 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs/consumer"
+	"github.com/swarajkumarsingh/turbo-deploy/infra/sqs/memory"
+)
+
+const testQueueURL = "memory://status"
+
+// TestStatusPipeline publishes build server status messages to the in-memory queue and consumes them with
+// handleMessage. It needs DB_URL to point at a migrated database: DB_URL=... go test -tags integration .
+func TestStatusPipeline(t *testing.T) {
+	ctx := context.Background()
+	queue := memory.New()
+	sqs.SetClient(queue)
+
+	projectId, deploymentId := seedDeployment(t, ctx)
+	for _, status := range []string{"PROG", "READY", "READY"} {
+		publishStatus(t, projectId, deploymentId, status)
+	}
+
+	// a single worker receiving one message at a time keeps the statuses in order
+	consume(t, queue, consumer.New(queue, consumer.Config{
+		QueueURL:        testQueueURL,
+		Workers:         1,
+		MaxMessages:     1,
+		WaitTimeSeconds: 1,
+	}, consumer.HandlerFunc(handleMessage)))
+
+	var deployment struct {
+		Status    string `db:"status"`
+		CommitSha string `db:"commit_sha"`
+		Branch    string `db:"branch"`
+	}
+	if err := database.GetContext(ctx, &deployment, `SELECT status, commit_sha, branch FROM deployments WHERE id = $1`, deploymentId); err != nil {
+		t.Fatal(err)
+	}
+	if deployment.Status != "READY" || deployment.CommitSha != "0123456789abcdef0123456789abcdef01234567" || deployment.Branch != "main" {
+		t.Fatalf("unexpected deployment %+v", deployment)
+	}
+
+	// the duplicate READY matches no transition, so it records neither an event nor an outbox record
+	var events, records int
+	if err := database.GetContext(ctx, &events, `SELECT COUNT(*) FROM deployment_status_events WHERE deployment_id = $1`, deploymentId); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.GetContext(ctx, &records, `SELECT COUNT(*) FROM outbox WHERE aggregate_type = 'deployment' AND aggregate_id = $1`,
+		strconv.Itoa(deploymentId)); err != nil {
+		t.Fatal(err)
+	}
+	if events != 2 || records != 2 {
+		t.Fatalf("got %d status events and %d outbox records, want 2 of each", events, records)
+	}
+}
+
+func publishStatus(t *testing.T, projectId, deploymentId int, status string) {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{
+		"appName":      "integration-test",
+		"projectId":    strconv.Itoa(projectId),
+		"deploymentId": strconv.Itoa(deploymentId),
+		"status":       status,
+		"host":         "localhost",
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+		"commitSha":    "0123456789abcdef0123456789abcdef01234567",
+		"branch":       "main",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sqs.SendMessage(string(body), testQueueURL); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// consume runs the consumer until every message was handled and deleted
+func consume(t *testing.T, queue *memory.Queue, worker *consumer.Consumer) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- worker.Run(ctx) }()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for queue.Len(testQueueURL) > 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if remaining := queue.Len(testQueueURL); remaining > 0 {
+		t.Fatalf("%d messages were not consumed", remaining)
+	}
+}
+
+// seedDeployment creates a user with a project and a queued deployment, they are removed when the test ends
+func seedDeployment(t *testing.T, ctx context.Context) (projectId, deploymentId int) {
+	t.Helper()
+	suffix := time.Now().UnixNano()
+
+	var userId int
+	err := database.GetContext(ctx, &userId, `INSERT INTO users(username, firstname, lastname, email, password, phone)
+		VALUES($1, 'integration', 'test', $2, '', '0000000000') RETURNING id`,
+		fmt.Sprintf("integration-%d", suffix), fmt.Sprintf("integration-%d@example.com", suffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		// projects, deployments and their events cascade
+		database.Exec(`DELETE FROM outbox WHERE aggregate_type = 'deployment' AND aggregate_id = $1`, strconv.Itoa(deploymentId))
+		database.Exec(`DELETE FROM users WHERE id = $1`, userId)
+	})
+
+	err = database.GetContext(ctx, &projectId, `INSERT INTO projects(user_id, name, source_code_url, subdomain)
+		VALUES($1, 'integration', 'https://github.com/example/site', $2) RETURNING id`, userId, fmt.Sprintf("integration%d", suffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = database.GetContext(ctx, &deploymentId, `INSERT INTO deployments(user_id, project_id) VALUES($1, $2) RETURNING id`,
+		userId, projectId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return projectId, deploymentId
+}